@@ -0,0 +1,198 @@
+package coreclient
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ABRRendition is a rendition of an adaptive bitrate ladder.
+type ABRRendition struct {
+	Name         string // e.g. "720p", used in the names of the playlist and the segments
+	Height       uint64 // the width is derived from the aspect ratio of the source
+	VideoBitrate uint64 // kbit/s
+	AudioBitrate uint64 // kbit/s, defaults to 128
+	FPS          float64
+}
+
+// ABRLadder describes a multi-rendition HLS process.
+type ABRLadder struct {
+	// Renditions are the renditions of the ladder. Renditions with a height above the height of
+	// the source are skipped.
+	Renditions []ABRRendition
+
+	// HLS are the settings for the HLS output. The LowLatency option is not supported.
+	HLS HLSPreset
+
+	// VideoCodec is the video encoder. Defaults to "libx264".
+	VideoCodec string
+
+	// Preset is the encoder preset. Defaults to "veryfast".
+	Preset string
+
+	// AudioCodec is the audio encoder. Defaults to "aac".
+	AudioCodec string
+}
+
+// DefaultABRRenditions is a common ladder from 1080p down to 360p.
+var DefaultABRRenditions = []ABRRendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 128},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 96},
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 64},
+}
+
+// Build generates a process config with the given ID that reads from the input and writes all
+// renditions into one HLS output with a master playlist. The probe of the input is used to skip
+// the renditions above the source resolution and to derive the GOP size from the frame rate.
+func (l ABRLadder) Build(id string, input api.ProcessConfigIO, probe api.Probe) (api.ProcessConfig, error) {
+	video := probe.VideoStreams()
+	if len(video) == 0 {
+		return api.ProcessConfig{}, fmt.Errorf("the source has no video stream")
+	}
+
+	source := video[0]
+	hasAudio := len(probe.AudioStreams()) != 0
+
+	if len(l.VideoCodec) == 0 {
+		l.VideoCodec = "libx264"
+	}
+
+	if len(l.Preset) == 0 {
+		l.Preset = "veryfast"
+	}
+
+	if len(l.AudioCodec) == 0 {
+		l.AudioCodec = "aac"
+	}
+
+	renditions := []ABRRendition{}
+	for _, r := range l.Renditions {
+		if source.Height != 0 && r.Height > source.Height {
+			continue
+		}
+
+		if r.AudioBitrate == 0 {
+			r.AudioBitrate = 128
+		}
+
+		renditions = append(renditions, r)
+	}
+
+	if len(renditions) == 0 {
+		return api.ProcessConfig{}, fmt.Errorf("all renditions are above the source resolution of %s", source.Resolution())
+	}
+
+	hls := l.HLS.withDefaults()
+	hls.LowLatency = false
+
+	placeholder, ok := filesystemPlaceholders[hls.Filesystem]
+	if !ok {
+		return api.ProcessConfig{}, fmt.Errorf("unknown filesystem: %s", hls.Filesystem)
+	}
+
+	fps := source.FPSValue()
+	if fps == 0 {
+		fps = 25
+	}
+
+	// The filter graph splits the video into one branch per rendition
+	filters := []string{}
+	split := fmt.Sprintf("[0:v:0]split=%d", len(renditions))
+	for i := range renditions {
+		split += fmt.Sprintf("[v%d]", i)
+	}
+	filters = append(filters, split)
+
+	for i, r := range renditions {
+		f := fmt.Sprintf("[v%d]scale=-2:%d", i, r.Height)
+		if r.FPS > 0 {
+			f += ",fps=" + strconv.FormatFloat(r.FPS, 'f', -1, 64)
+		}
+		f += fmt.Sprintf("[v%dout]", i)
+		filters = append(filters, f)
+	}
+
+	options := []string{}
+	streamMap := []string{}
+
+	for i, r := range renditions {
+		rfps := fps
+		if r.FPS > 0 {
+			rfps = r.FPS
+		}
+
+		gop := strconv.Itoa(int(math.Round(rfps * float64(hls.SegmentDuration))))
+		n := strconv.Itoa(i)
+
+		options = append(options,
+			"-map", "[v"+n+"out]",
+			"-c:v:"+n, l.VideoCodec,
+			"-b:v:"+n, strconv.FormatUint(r.VideoBitrate, 10)+"k",
+			"-maxrate:v:"+n, strconv.FormatUint(r.VideoBitrate*107/100, 10)+"k",
+			"-bufsize:v:"+n, strconv.FormatUint(r.VideoBitrate*3/2, 10)+"k",
+			"-preset:v:"+n, l.Preset,
+			"-g:v:"+n, gop,
+			"-keyint_min:v:"+n, gop,
+			"-sc_threshold:v:"+n, "0",
+		)
+
+		entry := "v:" + n
+
+		if hasAudio {
+			options = append(options,
+				"-map", "0:a:0",
+				"-c:a:"+n, l.AudioCodec,
+				"-b:a:"+n, strconv.FormatUint(r.AudioBitrate, 10)+"k",
+			)
+
+			entry += ",a:" + n
+		}
+
+		streamMap = append(streamMap, entry+",name:"+r.Name)
+	}
+
+	base := path.Base(hls.Name)
+
+	options = append(options,
+		"-f", "hls",
+		"-start_number", "0",
+		"-hls_time", strconv.FormatUint(uint64(hls.SegmentDuration), 10),
+		"-hls_list_size", strconv.FormatUint(uint64(hls.ListSize), 10),
+		"-hls_delete_threshold", "4",
+		"-hls_segment_type", "mpegts",
+		"-hls_flags", "append_list+delete_segments+program_date_time+temp_file+independent_segments",
+		"-hls_segment_filename", placeholder+hls.Name+"_seg_%v_%04d.ts",
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-master_pl_name", base+".m3u8",
+		"-master_pl_publish_rate", "2",
+	)
+
+	cleanup := hls.Cleanup()
+	cleanup[0].MaxFiles *= uint(len(renditions))
+
+	config := api.ProcessConfig{
+		ID:      id,
+		Type:    "ffmpeg",
+		Options: []string{"-err_detect", "ignore_err", "-filter_complex", strings.Join(filters, ";")},
+		Input:   []api.ProcessConfigIO{input},
+		Output: []api.ProcessConfigIO{
+			{
+				ID:      "output_0",
+				Address: placeholder + hls.Name + "_%v.m3u8",
+				Options: options,
+				Cleanup: cleanup,
+			},
+		},
+		Reconnect:      true,
+		ReconnectDelay: 15,
+		Autostart:      true,
+		StaleTimeout:   30,
+	}
+
+	return config, nil
+}
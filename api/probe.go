@@ -2,6 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ProbeIO represents a stream of a probed file
@@ -36,3 +41,181 @@ type Probe struct {
 	Streams []ProbeIO `json:"streams"`
 	Log     []string  `json:"log"`
 }
+
+// BitrateValue returns the bitrate in kbit/s, or 0 if it is not known.
+func (p ProbeIO) BitrateValue() float64 {
+	f, _ := p.Bitrate.Float64()
+	return f
+}
+
+// DurationValue returns the duration, or 0 if it is not known.
+func (p ProbeIO) DurationValue() time.Duration {
+	f, _ := p.Duration.Float64()
+	return time.Duration(f * float64(time.Second))
+}
+
+// FPSValue returns the frames per second, or 0 if it is not known.
+func (p ProbeIO) FPSValue() float64 {
+	f, _ := p.FPS.Float64()
+	return f
+}
+
+// Resolution returns the resolution of a video stream, e.g. "1920x1080".
+func (p ProbeIO) Resolution() string {
+	if p.Width == 0 && p.Height == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dx%d", p.Width, p.Height)
+}
+
+// String returns a short description of the stream, e.g. "video h264 1920x1080 25fps 4500kbit/s".
+func (p ProbeIO) String() string {
+	parts := []string{p.Type, p.Codec}
+
+	switch p.Type {
+	case "video":
+		if r := p.Resolution(); len(r) != 0 {
+			parts = append(parts, r)
+		}
+
+		if fps := p.FPSValue(); fps != 0 {
+			parts = append(parts, strconv.FormatFloat(fps, 'f', -1, 64)+"fps")
+		}
+	case "audio":
+		if p.Sampling != 0 {
+			parts = append(parts, fmt.Sprintf("%dHz", p.Sampling))
+		}
+
+		if len(p.Layout) != 0 {
+			parts = append(parts, p.Layout)
+		}
+	}
+
+	if b := p.BitrateValue(); b != 0 {
+		parts = append(parts, strconv.FormatFloat(b, 'f', -1, 64)+"kbit/s")
+	}
+
+	if len(p.Language) != 0 && p.Language != "und" {
+		parts = append(parts, "("+p.Language+")")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// StreamsOfType returns all streams of the given type, e.g. "video".
+func (p Probe) StreamsOfType(t string) []ProbeIO {
+	streams := []ProbeIO{}
+
+	for _, s := range p.Streams {
+		if s.Type == t {
+			streams = append(streams, s)
+		}
+	}
+
+	return streams
+}
+
+// VideoStreams returns all video streams.
+func (p Probe) VideoStreams() []ProbeIO {
+	return p.StreamsOfType("video")
+}
+
+// AudioStreams returns all audio streams.
+func (p Probe) AudioStreams() []ProbeIO {
+	return p.StreamsOfType("audio")
+}
+
+// SubtitleStreams returns all subtitle streams.
+func (p Probe) SubtitleStreams() []ProbeIO {
+	return p.StreamsOfType("subtitle")
+}
+
+// Summary returns a one line description of all streams.
+func (p Probe) Summary() string {
+	parts := make([]string, 0, len(p.Streams))
+
+	for _, s := range p.Streams {
+		parts = append(parts, s.String())
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// ProbeDifference is a difference between two probes.
+type ProbeDifference struct {
+	Stream string // e.g. "video #0"
+	Field  string // e.g. "resolution"
+	Old    string
+	New    string
+}
+
+func (d ProbeDifference) String() string {
+	return fmt.Sprintf("%s: %s changed from %s to %s", d.Stream, d.Field, d.Old, d.New)
+}
+
+// CompareProbes reports the meaningful differences between two probes, e.g. a changed resolution
+// or codec. Streams are compared per type in the order they appear. The bitrate and the frame rate
+// are only reported if they changed by more than 10%.
+func CompareProbes(a, b Probe) []ProbeDifference {
+	diffs := []ProbeDifference{}
+
+	for _, t := range []string{"video", "audio", "subtitle"} {
+		sa, sb := a.StreamsOfType(t), b.StreamsOfType(t)
+
+		n := len(sa)
+		if len(sb) > n {
+			n = len(sb)
+		}
+
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("%s #%d", t, i)
+
+			if i >= len(sa) {
+				diffs = append(diffs, ProbeDifference{Stream: name, Field: "stream", Old: "none", New: sb[i].String()})
+				continue
+			}
+
+			if i >= len(sb) {
+				diffs = append(diffs, ProbeDifference{Stream: name, Field: "stream", Old: sa[i].String(), New: "none"})
+				continue
+			}
+
+			x, y := sa[i], sb[i]
+
+			add := func(field, old, new string) {
+				if old != new {
+					diffs = append(diffs, ProbeDifference{Stream: name, Field: field, Old: old, New: new})
+				}
+			}
+
+			addFloat := func(field string, old, new float64) {
+				if old == 0 && new == 0 {
+					return
+				}
+
+				if math.Abs(old-new) > 0.1*math.Max(old, new) {
+					add(field, strconv.FormatFloat(old, 'f', -1, 64), strconv.FormatFloat(new, 'f', -1, 64))
+				}
+			}
+
+			add("codec", x.Codec, y.Codec)
+			add("language", x.Language, y.Language)
+
+			switch t {
+			case "video":
+				add("resolution", x.Resolution(), y.Resolution())
+				add("pix_fmt", x.Pixfmt, y.Pixfmt)
+				addFloat("fps", x.FPSValue(), y.FPSValue())
+			case "audio":
+				add("sampling_hz", strconv.FormatUint(x.Sampling, 10), strconv.FormatUint(y.Sampling, 10))
+				add("layout", x.Layout, y.Layout)
+				add("channels", strconv.FormatUint(x.Channels, 10), strconv.FormatUint(y.Channels, 10))
+			}
+
+			addFloat("bitrate_kbps", x.BitrateValue(), y.BitrateValue())
+		}
+	}
+
+	return diffs
+}
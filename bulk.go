@@ -0,0 +1,173 @@
+package coreclient
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// BulkResult is the result of a bulk operation for a single process.
+type BulkResult struct {
+	ID  string
+	Err error
+}
+
+// BulkReport is the aggregated result of a bulk operation. The results are in the
+// same order as the processes have been selected.
+type BulkReport struct {
+	Results []BulkResult
+}
+
+// Succeeded returns the IDs of the processes where the operation succeeded.
+func (b BulkReport) Succeeded() []string {
+	ids := []string{}
+
+	for _, r := range b.Results {
+		if r.Err == nil {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	return ids
+}
+
+// Failed returns the results of the processes where the operation failed.
+func (b BulkReport) Failed() []BulkResult {
+	failed := []BulkResult{}
+
+	for _, r := range b.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+// Err returns an error summarizing all failed operations, or nil if all succeeded.
+func (b BulkReport) Err() error {
+	failed := b.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(failed))
+	for _, r := range failed {
+		msgs = append(msgs, r.ID+": "+r.Err.Error())
+	}
+
+	return fmt.Errorf("%d of %d operations failed: %s", len(failed), len(b.Results), strings.Join(msgs, "; "))
+}
+
+// BulkOptions are the options for the bulk operations.
+type BulkOptions struct {
+	// Select selects the processes the operation will be applied to. An empty selection
+	// is rejected, unless All is set.
+	Select ProcessListOptions
+
+	// All allows an empty selection, i.e. applies the operation to all processes.
+	All bool
+
+	// Concurrency is the max. number of operations that run in parallel. Defaults to 10.
+	Concurrency int
+}
+
+// ProcessCommandBulk sends the command to all selected processes.
+func ProcessCommandBulk(client RestClient, opts BulkOptions, command string) (BulkReport, error) {
+	return bulk(client, opts, "state", func(p api.Process) error {
+		return client.ProcessCommand(p.ID, command)
+	})
+}
+
+// ProcessDeleteBulk deletes all selected processes.
+func ProcessDeleteBulk(client RestClient, opts BulkOptions) (BulkReport, error) {
+	return bulk(client, opts, "state", func(p api.Process) error {
+		return client.ProcessDelete(p.ID)
+	})
+}
+
+// ProcessMetadataSetBulk stores the metadata under the key for all selected processes.
+func ProcessMetadataSetBulk(client RestClient, opts BulkOptions, key string, metadata api.Metadata) (BulkReport, error) {
+	return bulk(client, opts, "state", func(p api.Process) error {
+		return client.ProcessMetadataSet(p.ID, key, metadata)
+	})
+}
+
+// ProcessUpdateBulk calls update with the config of every selected process and updates the process
+// with the returned config. Processes where the returned config doesn't differ from the current
+// config will not be updated.
+func ProcessUpdateBulk(client RestClient, opts BulkOptions, update func(config api.ProcessConfig) (api.ProcessConfig, error)) (BulkReport, error) {
+	return bulk(client, opts, "config", func(p api.Process) error {
+		if p.Config == nil {
+			return fmt.Errorf("no config available")
+		}
+
+		config, err := update(*p.Config)
+		if err != nil {
+			return err
+		}
+
+		if ProcessConfigEqual(*p.Config, config) {
+			return nil
+		}
+
+		return client.ProcessUpdate(p.ID, config)
+	})
+}
+
+// bulk selects the processes with the given filter and applies fn to each of them with
+// bounded concurrency. The returned error is only non-nil if the selection failed. The client
+// must be safe for concurrent use, as the client returned by New is.
+func bulk(client RestClient, opts BulkOptions, filter string, fn func(p api.Process) error) (BulkReport, error) {
+	report := BulkReport{}
+
+	if !opts.All && isEmptySelection(opts.Select) {
+		return report, fmt.Errorf("empty selection, set All to apply the operation to all processes")
+	}
+
+	list := opts.Select
+	list.Filter = []string{filter}
+
+	processes, err := client.ProcessList(list)
+	if err != nil {
+		return report, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+
+	report.Results = make([]BulkResult, len(processes))
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, p := range processes {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, p api.Process) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			report.Results[i] = BulkResult{
+				ID:  p.ID,
+				Err: fn(p),
+			}
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	return report, nil
+}
+
+// isEmptySelection returns whether the options select all processes.
+func isEmptySelection(s ProcessListOptions) bool {
+	return len(s.ID) == 0 && len(s.Reference) == 0 && len(s.IDPattern) == 0 && len(s.RefPattern) == 0
+}
@@ -0,0 +1,203 @@
+package coreclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+const (
+	channelIngestPrefix = "restreamer-ui:ingest:"
+	channelEgressPrefix = "restreamer-ui:egress:"
+)
+
+// ChannelState is the aggregated state of all processes of a channel.
+type ChannelState string
+
+const (
+	CHANNEL_RUNNING  ChannelState = "running"
+	CHANNEL_DEGRADED ChannelState = "degraded"
+	CHANNEL_STOPPED  ChannelState = "stopped"
+)
+
+// Channel is a group of processes sharing the same reference, as in the Restreamer: an ingest
+// process with the ID "restreamer-ui:ingest:{reference}" (plus auxiliary ingest processes like
+// snapshots with the same prefix) and several egress processes with the ID
+// "restreamer-ui:egress:{name}:{reference}".
+type Channel struct {
+	client    RestClient
+	reference string
+
+	// Ingest and Egress are the processes of the channel as of the last Load.
+	Ingest []api.Process
+	Egress []api.Process
+}
+
+// LoadChannel loads all processes with the given reference.
+func LoadChannel(client RestClient, reference string) (*Channel, error) {
+	c := &Channel{
+		client:    client,
+		reference: reference,
+	}
+
+	if err := c.Load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reference returns the reference of the channel.
+func (c *Channel) Reference() string {
+	return c.reference
+}
+
+// IngestID returns the ID of the ingest process of the channel.
+func (c *Channel) IngestID() string {
+	return channelIngestPrefix + c.reference
+}
+
+// EgressID returns the ID of the egress process with the given name.
+func (c *Channel) EgressID(name string) string {
+	return channelEgressPrefix + name + ":" + c.reference
+}
+
+// Load reloads the processes of the channel.
+func (c *Channel) Load() error {
+	processes, err := c.client.ProcessList(ProcessListOptions{
+		Reference: c.reference,
+		Filter:    []string{"config", "state"},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Ingest = []api.Process{}
+	c.Egress = []api.Process{}
+
+	for _, p := range processes {
+		if p.Reference != c.reference {
+			continue
+		}
+
+		if strings.HasPrefix(p.ID, channelIngestPrefix) {
+			c.Ingest = append(c.Ingest, p)
+		} else {
+			c.Egress = append(c.Egress, p)
+		}
+	}
+
+	// The main ingest process comes first
+	sort.SliceStable(c.Ingest, func(i, j int) bool {
+		return c.Ingest[i].ID == c.IngestID() && c.Ingest[j].ID != c.IngestID()
+	})
+
+	return nil
+}
+
+// Processes returns all processes of the channel, ingest processes first.
+func (c *Channel) Processes() []api.Process {
+	return append(append([]api.Process{}, c.Ingest...), c.Egress...)
+}
+
+// State returns the aggregated state of the channel as of the last Load. The channel is running
+// if all processes are running, stopped if none is running, and degraded otherwise.
+func (c *Channel) State() ChannelState {
+	processes := c.Processes()
+	running := 0
+
+	for _, p := range processes {
+		if p.State != nil && p.State.State == "running" {
+			running++
+		}
+	}
+
+	if running == 0 {
+		return CHANNEL_STOPPED
+	}
+
+	if running == len(processes) {
+		return CHANNEL_RUNNING
+	}
+
+	return CHANNEL_DEGRADED
+}
+
+// Start starts the ingest processes, waits for them to run and then starts the egress processes.
+func (c *Channel) Start(ctx context.Context) error {
+	if err := c.Load(); err != nil {
+		return err
+	}
+
+	for _, p := range c.Ingest {
+		if err := c.client.ProcessCommand(p.ID, "start"); err != nil {
+			return fmt.Errorf("starting %s: %w", p.ID, err)
+		}
+	}
+
+	for _, p := range c.Ingest {
+		if _, err := WaitForState(ctx, c.client, p.ID, "running"); err != nil {
+			return fmt.Errorf("waiting for %s: %w", p.ID, err)
+		}
+	}
+
+	for _, p := range c.Egress {
+		if err := c.client.ProcessCommand(p.ID, "start"); err != nil {
+			return fmt.Errorf("starting %s: %w", p.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Stop stops the egress processes first and then the ingest processes.
+func (c *Channel) Stop() error {
+	if err := c.Load(); err != nil {
+		return err
+	}
+
+	for _, p := range c.Egress {
+		if err := c.client.ProcessCommand(p.ID, "stop"); err != nil {
+			return fmt.Errorf("stopping %s: %w", p.ID, err)
+		}
+	}
+
+	for _, p := range c.Ingest {
+		if err := c.client.ProcessCommand(p.ID, "stop"); err != nil {
+			return fmt.Errorf("stopping %s: %w", p.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// AddEgress adds an egress process with the given name to the channel. The ID and the
+// reference of the config will be set accordingly.
+func (c *Channel) AddEgress(name string, config api.ProcessConfig) error {
+	config.ID = c.EgressID(name)
+	config.Reference = c.reference
+
+	if err := c.client.ProcessAdd(config); err != nil {
+		return err
+	}
+
+	return c.Load()
+}
+
+// RemoveEgress stops and removes the egress process with the given name from the channel.
+func (c *Channel) RemoveEgress(name string) error {
+	id := c.EgressID(name)
+
+	if err := c.client.ProcessCommand(id, "stop"); err != nil {
+		return err
+	}
+
+	if err := c.client.ProcessDelete(id); err != nil {
+		return err
+	}
+
+	return c.Load()
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/datarhei/core-client-go/v16/api"
@@ -20,6 +21,15 @@ const (
 	coreversion = "^16.7.2" // first public release
 )
 
+// VersionError is returned if a method is not available in the version of the connected core.
+type VersionError struct {
+	Constraint string
+}
+
+func (e VersionError) Error() string {
+	return fmt.Sprintf("this method is only available in version %s of the core", e.Constraint)
+}
+
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -78,6 +88,7 @@ type RestClient interface {
 	ProcessDelete(id string) error                                  // DELETE /v3/process/{id}
 	ProcessCommand(id, command string) error                        // PUT /v3/process/{id}/command
 	ProcessProbe(id string) (api.Probe, error)                      // GET /v3/process/{id}/probe
+	ProcessProbeConfig(p api.ProcessConfig) (api.Probe, error)      // POST /v3/process/probe
 	ProcessConfig(id string) (api.ProcessConfig, error)             // GET /v3/process/{id}/config
 	ProcessReport(id string) (api.ProcessReport, error)             // GET /v3/process/{id}/report
 	ProcessState(id string) (api.ProcessState, error)               // GET /v3/process/{id}/state
@@ -128,6 +139,10 @@ type restclient struct {
 	client       HTTPClient
 	about        api.About
 
+	// lock guards the tokens, about and the version of the connected core, which
+	// are updated on a login or a token refresh.
+	lock sync.RWMutex
+
 	version struct {
 		connectedCore *semver.Version
 		methods       map[string]*semver.Constraints
@@ -135,7 +150,7 @@ type restclient struct {
 }
 
 // New returns a new REST API client for the given config. The error is non-nil
-// in case of an error.
+// in case of an error. The client is safe for concurrent use.
 func New(config Config) (RestClient, error) {
 	r := &restclient{
 		address:      config.Address,
@@ -171,8 +186,9 @@ func New(config Config) (RestClient, error) {
 	}
 
 	r.version.methods = map[string]*semver.Constraints{
-		"GET/api/v3/srt":     mustNewConstraint("^16.9.0"),
-		"GET/api/v3/metrics": mustNewConstraint("^16.10.0"),
+		"GET/api/v3/srt":            mustNewConstraint("^16.9.0"),
+		"GET/api/v3/metrics":        mustNewConstraint("^16.10.0"),
+		"POST/api/v3/process/probe": mustNewConstraint("^16.14.0"),
 	}
 
 	if len(r.about.ID) != 0 {
@@ -205,15 +221,24 @@ func New(config Config) (RestClient, error) {
 	return r, nil
 }
 
-func (r restclient) String() string {
+func (r *restclient) String() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	return fmt.Sprintf("%s %s (%s) %s @ %s", r.about.Name, r.about.Version.Number, r.about.Version.Arch, r.about.ID, r.address)
 }
 
 func (r *restclient) ID() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	return r.about.ID
 }
 
 func (r *restclient) Tokens() (string, string) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	return r.accessToken, r.refreshToken
 }
 
@@ -222,6 +247,9 @@ func (r *restclient) Address() string {
 }
 
 func (r *restclient) About() api.About {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	return r.about
 }
 
@@ -330,8 +358,11 @@ func (r *restclient) checkVersion(method, path string) error {
 		return nil
 	}
 
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	if !c.Check(r.version.connectedCore) {
-		return fmt.Errorf("this method is only available in version %s of the core", c.String())
+		return VersionError{Constraint: c.String()}
 	}
 
 	return nil
@@ -420,19 +451,30 @@ func (r *restclient) stream(method, path, contentType string, data io.Reader) (i
 		req.Header.Add("Content-Type", contentType)
 	}
 
-	if len(r.accessToken) != 0 {
-		req.Header.Add("Authorization", "Bearer "+r.accessToken)
+	r.lock.RLock()
+	accessToken := r.accessToken
+	r.lock.RUnlock()
+
+	if len(accessToken) != 0 {
+		req.Header.Add("Authorization", "Bearer "+accessToken)
 	}
 
 	status, body, err := r.request(req)
 	if status == http.StatusUnauthorized {
-		if err := r.refresh(); err != nil {
-			if err := r.login(); err != nil {
-				return nil, err
+		r.lock.Lock()
+		// Another request may already have renewed the token in the meantime
+		if r.accessToken == accessToken {
+			if err := r.refresh(); err != nil {
+				if err := r.login(); err != nil {
+					r.lock.Unlock()
+					return nil, err
+				}
 			}
 		}
+		accessToken = r.accessToken
+		r.lock.Unlock()
 
-		req.Header.Set("Authorization", "Bearer "+r.accessToken)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
 		status, body, err = r.request(req)
 	}
 
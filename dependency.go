@@ -0,0 +1,308 @@
+package coreclient
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// DependenciesMetadataKey is the process metadata key for explicit dependencies of a process. The
+// value is a list of process IDs the process depends on.
+const DependenciesMetadataKey = "dependencies"
+
+var placeholderRegex = regexp.MustCompile(`^\{(rtmp|srt)(?:,([^}]*))?\}`)
+
+// endpoint is a normalized address an output writes to or an input reads from.
+type endpoint struct {
+	kind string // "rtmp", "srt", "mem", "disk" or "url"
+	name string
+}
+
+// parseEndpoint normalizes an input or output address. Placeholders for the internal RTMP
+// and SRT servers are resolved by their name, placeholders for the filesystems by their path.
+func parseEndpoint(address string) endpoint {
+	if m := placeholderRegex.FindStringSubmatch(address); m != nil {
+		for _, param := range strings.Split(m[2], ",") {
+			if key, value, found := strings.Cut(param, "="); found && key == "name" {
+				return endpoint{kind: m[1], name: strings.TrimSuffix(value, ".stream")}
+			}
+		}
+	}
+
+	for prefix, kind := range map[string]string{"{memfs}": "mem", "{diskfs}": "disk"} {
+		if strings.HasPrefix(address, prefix) {
+			return endpoint{kind: kind, name: path.Clean("/" + strings.TrimPrefix(address, prefix))}
+		}
+	}
+
+	return endpoint{kind: "url", name: address}
+}
+
+// ProcessGraph is a dependency graph of processes. A process depends on another process if one of its
+// inputs reads from an output of the other process, or if it is explicitly listed in the metadata of
+// the process under DependenciesMetadataKey.
+type ProcessGraph struct {
+	processes map[string]api.Process
+	deps      map[string]map[string]struct{} // process ID -> IDs of the processes it depends on
+
+	// ready are the endpoints of a process another process reads from
+	ready map[string][]endpoint
+}
+
+// NewProcessGraph builds the dependency graph for the processes. The processes need to include
+// their config, and their metadata for explicit dependencies. Dependencies on processes that are
+// not in the list are ignored.
+func NewProcessGraph(processes []api.Process) *ProcessGraph {
+	g := &ProcessGraph{
+		processes: map[string]api.Process{},
+		deps:      map[string]map[string]struct{}{},
+		ready:     map[string][]endpoint{},
+	}
+
+	producers := map[endpoint]string{}
+
+	for _, p := range processes {
+		g.processes[p.ID] = p
+		g.deps[p.ID] = map[string]struct{}{}
+
+		if p.Config == nil {
+			continue
+		}
+
+		for _, output := range p.Config.Output {
+			producers[parseEndpoint(output.Address)] = p.ID
+		}
+	}
+
+	for _, p := range processes {
+		if p.Config != nil {
+			for _, input := range p.Config.Input {
+				e := parseEndpoint(input.Address)
+
+				producer, ok := producers[e]
+				if !ok || producer == p.ID {
+					continue
+				}
+
+				g.deps[p.ID][producer] = struct{}{}
+				g.ready[producer] = append(g.ready[producer], e)
+			}
+		}
+
+		if m, ok := p.Metadata.(map[string]interface{}); ok {
+			ids := []string{}
+			if decodeMetadata(m[DependenciesMetadataKey], &ids) == nil {
+				for _, id := range ids {
+					if _, ok := g.processes[id]; ok && id != p.ID {
+						g.deps[p.ID][id] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// LoadProcessGraph builds the dependency graph for the processes selected by opts.
+func LoadProcessGraph(client RestClient, opts ProcessListOptions) (*ProcessGraph, error) {
+	opts.Filter = []string{"config", "metadata"}
+
+	processes, err := client.ProcessList(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProcessGraph(processes), nil
+}
+
+// Dependencies returns the IDs of the processes the process with the given ID depends on.
+func (g *ProcessGraph) Dependencies(id string) []string {
+	ids := []string{}
+
+	for dep := range g.deps[id] {
+		ids = append(ids, dep)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Order returns the processes grouped in levels, such that every process only depends on
+// processes in previous levels. It returns an error if the graph contains a cycle.
+func (g *ProcessGraph) Order() ([][]string, error) {
+	remaining := map[string]map[string]struct{}{}
+	for id, deps := range g.deps {
+		remaining[id] = map[string]struct{}{}
+		for dep := range deps {
+			remaining[id][dep] = struct{}{}
+		}
+	}
+
+	levels := [][]string{}
+
+	for len(remaining) != 0 {
+		level := []string{}
+
+		for id, deps := range remaining {
+			if len(deps) == 0 {
+				level = append(level, id)
+			}
+		}
+
+		if len(level) == 0 {
+			return nil, fmt.Errorf("dependency cycle: %s", strings.Join(g.cycle(remaining), " -> "))
+		}
+
+		sort.Strings(level)
+
+		for _, id := range level {
+			delete(remaining, id)
+		}
+
+		for _, deps := range remaining {
+			for _, id := range level {
+				delete(deps, id)
+			}
+		}
+
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// cycle returns a cycle in the remaining graph, where every node has at least one dependency.
+func (g *ProcessGraph) cycle(remaining map[string]map[string]struct{}) []string {
+	ids := []string{}
+	for id := range remaining {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	visited := map[string]int{}
+	path := []string{}
+
+	id := ids[0]
+	for {
+		if i, ok := visited[id]; ok {
+			return append(path[i:], id)
+		}
+
+		visited[id] = len(path)
+		path = append(path, id)
+
+		next := []string{}
+		for dep := range remaining[id] {
+			next = append(next, dep)
+		}
+
+		sort.Strings(next)
+		id = next[0]
+	}
+}
+
+// Start starts the processes level by level. Before the next level is started, it waits for all
+// processes of the current level to be ready, i.e. to run and to provide the outputs other processes
+// read from (the RTMP or SRT stream is published, the file exists on the filesystem).
+func (g *ProcessGraph) Start(ctx context.Context, client RestClient) error {
+	levels, err := g.Order()
+	if err != nil {
+		return err
+	}
+
+	for _, level := range levels {
+		for _, id := range level {
+			if err := client.ProcessCommand(id, "start"); err != nil {
+				return fmt.Errorf("starting %s: %w", id, err)
+			}
+		}
+
+		for _, id := range level {
+			if _, err := WaitForState(ctx, client, id, "running"); err != nil {
+				return fmt.Errorf("waiting for %s: %w", id, err)
+			}
+
+			for _, e := range g.ready[id] {
+				if err := waitForEndpoint(ctx, client, e); err != nil {
+					return fmt.Errorf("waiting for %s of %s: %w", e.name, id, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Stop stops the processes level by level in reverse order, i.e. processes are stopped
+// before the processes they depend on.
+func (g *ProcessGraph) Stop(ctx context.Context, client RestClient) error {
+	levels, err := g.Order()
+	if err != nil {
+		return err
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, id := range levels[i] {
+			if err := client.ProcessCommand(id, "stop"); err != nil {
+				return fmt.Errorf("stopping %s: %w", id, err)
+			}
+		}
+
+		for _, id := range levels[i] {
+			if _, err := WaitForState(ctx, client, id, "finished", "failed", "killed"); err != nil {
+				return fmt.Errorf("waiting for %s: %w", id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitForEndpoint waits until the endpoint is available.
+func waitForEndpoint(ctx context.Context, client RestClient, e endpoint) error {
+	return poll(ctx, func() (bool, error) {
+		switch e.kind {
+		case "rtmp":
+			channels, err := client.RTMPChannels()
+			if err != nil {
+				return false, err
+			}
+
+			for _, c := range channels {
+				name := strings.TrimSuffix(path.Base(c.Name), ".stream")
+				if name == e.name || c.Name == e.name {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		case "srt":
+			channels, err := client.SRTChannels()
+			if err != nil {
+				return false, err
+			}
+
+			for resource := range channels.Publisher {
+				if resource == e.name {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		case "mem":
+			return client.MemFSHasFile(e.name), nil
+		case "disk":
+			return client.DiskFSHasFile(e.name), nil
+		}
+
+		return true, nil
+	})
+}
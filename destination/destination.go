@@ -0,0 +1,380 @@
+// Package destination models push targets for restreaming, like YouTube, Twitch, Facebook or
+// custom RTMP and SRT endpoints, and generates process outputs for them.
+package destination
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// Protocol is the protocol of a destination.
+type Protocol string
+
+const (
+	RTMP  Protocol = "rtmp"
+	RTMPS Protocol = "rtmps"
+	SRT   Protocol = "srt"
+)
+
+// Constraints are the requirements of a destination on the stream.
+type Constraints struct {
+	VideoCodecs     []string // allowed video codecs, e.g. "h264". Empty allows any codec.
+	AudioCodecs     []string // allowed audio codecs, e.g. "aac". Empty allows any codec.
+	MaxVideoBitrate uint64   // kbit/s, 0 for no limit
+	MaxHeight       uint64   // 0 for no limit
+	KeyframeSeconds uint     // max. keyframe interval in seconds, 0 for no requirement
+}
+
+// Destination is a push target for a stream.
+type Destination struct {
+	Name        string
+	Protocol    Protocol
+	Server      string // e.g. "rtmp://a.rtmp.youtube.com/live2"
+	StreamKey   string // appended to the server for RTMP(S), the streamid for SRT
+	Passphrase  string // SRT only
+	Constraints Constraints
+}
+
+var (
+	flvConstraints = Constraints{
+		VideoCodecs:     []string{"h264"},
+		AudioCodecs:     []string{"aac", "mp3"},
+		KeyframeSeconds: 2,
+	}
+)
+
+// YouTube returns a destination for YouTube Live via RTMPS.
+func YouTube(key string) Destination {
+	return Destination{
+		Name:        "youtube",
+		Protocol:    RTMPS,
+		Server:      "rtmps://a.rtmp.youtube.com/live2",
+		StreamKey:   key,
+		Constraints: flvConstraints,
+	}
+}
+
+// Twitch returns a destination for Twitch via RTMP.
+func Twitch(key string) Destination {
+	c := flvConstraints
+	c.AudioCodecs = []string{"aac"}
+	c.MaxVideoBitrate = 6000
+	c.MaxHeight = 1080
+
+	return Destination{
+		Name:        "twitch",
+		Protocol:    RTMP,
+		Server:      "rtmp://live.twitch.tv/app",
+		StreamKey:   key,
+		Constraints: c,
+	}
+}
+
+// Facebook returns a destination for Facebook Live via RTMPS.
+func Facebook(key string) Destination {
+	c := flvConstraints
+	c.AudioCodecs = []string{"aac"}
+	c.MaxVideoBitrate = 4000
+	c.MaxHeight = 1080
+
+	return Destination{
+		Name:        "facebook",
+		Protocol:    RTMPS,
+		Server:      "rtmps://live-api-s.facebook.com:443/rtmp",
+		StreamKey:   key,
+		Constraints: c,
+	}
+}
+
+// Custom returns a destination for an arbitrary RTMP or RTMPS server. The protocol
+// is derived from the scheme of the server URL.
+func Custom(name, server, key string) (Destination, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return Destination{}, err
+	}
+
+	d := Destination{
+		Name:        name,
+		Server:      strings.TrimSuffix(server, "/"),
+		StreamKey:   key,
+		Constraints: flvConstraints,
+	}
+
+	switch u.Scheme {
+	case "rtmp":
+		d.Protocol = RTMP
+	case "rtmps":
+		d.Protocol = RTMPS
+	case "srt":
+		d.Protocol = SRT
+		d.Constraints = Constraints{}
+	default:
+		return Destination{}, fmt.Errorf("unsupported protocol: %s", u.Scheme)
+	}
+
+	return d, nil
+}
+
+// SRTListener returns a destination for an SRT listener with the given address (host:port).
+func SRTListener(name, address, streamid, passphrase string) Destination {
+	return Destination{
+		Name:       name,
+		Protocol:   SRT,
+		Server:     "srt://" + strings.TrimPrefix(address, "srt://"),
+		StreamKey:  streamid,
+		Passphrase: passphrase,
+	}
+}
+
+// Address returns the full address of the destination, including the stream key.
+func (d Destination) Address() string {
+	if d.Protocol != SRT {
+		if len(d.StreamKey) == 0 {
+			return d.Server
+		}
+
+		return d.Server + "/" + d.StreamKey
+	}
+
+	u, err := url.Parse(d.Server)
+	if err != nil {
+		u = &url.URL{Opaque: d.Server}
+	}
+
+	// Parameters that are already in the server address are kept, except for the secrets
+	values := u.Query()
+
+	if !values.Has("mode") {
+		values.Set("mode", "caller")
+	}
+
+	if !values.Has("transtype") {
+		values.Set("transtype", "live")
+	}
+
+	if len(d.StreamKey) != 0 {
+		values.Set("streamid", d.StreamKey)
+	}
+
+	if len(d.Passphrase) != 0 {
+		values.Set("passphrase", d.Passphrase)
+	}
+
+	u.RawQuery = values.Encode()
+
+	return u.String()
+}
+
+// MaskedAddress returns the address of the destination with the secrets masked.
+func (d Destination) MaskedAddress() string {
+	return d.Mask(d.Address())
+}
+
+// String returns the name and the masked address of the destination.
+func (d Destination) String() string {
+	return d.Name + " (" + d.MaskedAddress() + ")"
+}
+
+// Mask replaces all occurrences of the stream key and the passphrase in s.
+func (d Destination) Mask(s string) string {
+	for _, secret := range []string{d.StreamKey, d.Passphrase} {
+		if len(secret) == 0 {
+			continue
+		}
+
+		s = strings.ReplaceAll(s, secret, mask(secret))
+		s = strings.ReplaceAll(s, url.QueryEscape(secret), mask(secret))
+	}
+
+	return s
+}
+
+func mask(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+
+	return secret[:4] + "****"
+}
+
+// muxer returns the muxer options for the protocol.
+func (d Destination) muxer() []string {
+	if d.Protocol == SRT {
+		return []string{"-f", "mpegts"}
+	}
+
+	return []string{"-f", "flv", "-flvflags", "no_duration_filesize"}
+}
+
+// Output returns an output that copies the streams to the destination.
+func (d Destination) Output(id string) api.ProcessConfigIO {
+	options := []string{"-map", "0:v:0?", "-map", "0:a:0?", "-c", "copy"}
+
+	return api.ProcessConfigIO{
+		ID:      id,
+		Address: d.Address(),
+		Options: append(options, d.muxer()...),
+	}
+}
+
+// Check returns the violations of the constraints of the destination by the probed source.
+func (d Destination) Check(probe api.Probe) []string {
+	violations := []string{}
+	c := d.Constraints
+
+	for _, s := range probe.VideoStreams() {
+		if len(c.VideoCodecs) != 0 && !contains(c.VideoCodecs, s.Codec) {
+			violations = append(violations, fmt.Sprintf("video codec %s is not supported (%s)", s.Codec, strings.Join(c.VideoCodecs, ", ")))
+		}
+
+		if c.MaxHeight != 0 && s.Height > c.MaxHeight {
+			violations = append(violations, fmt.Sprintf("video height %d is above %d", s.Height, c.MaxHeight))
+		}
+
+		if b := s.BitrateValue(); c.MaxVideoBitrate != 0 && b > float64(c.MaxVideoBitrate) {
+			violations = append(violations, fmt.Sprintf("video bitrate %.0fkbit/s is above %dkbit/s", b, c.MaxVideoBitrate))
+		}
+	}
+
+	for _, s := range probe.AudioStreams() {
+		if len(c.AudioCodecs) != 0 && !contains(c.AudioCodecs, s.Codec) {
+			violations = append(violations, fmt.Sprintf("audio codec %s is not supported (%s)", s.Codec, strings.Join(c.AudioCodecs, ", ")))
+		}
+	}
+
+	return violations
+}
+
+// TranscodingOutput returns an output that transcodes the streams as required by the constraints
+// of the destination. Streams that already satisfy the constraints are copied. The fps of the source
+// is used to derive the keyframe interval.
+func (d Destination) TranscodingOutput(id string, probe api.Probe) api.ProcessConfigIO {
+	c := d.Constraints
+	options := []string{}
+
+	if video := probe.VideoStreams(); len(video) != 0 {
+		s := video[0]
+		options = append(options, "-map", "0:v:0")
+
+		transcode := (len(c.VideoCodecs) != 0 && !contains(c.VideoCodecs, s.Codec)) ||
+			(c.MaxHeight != 0 && s.Height > c.MaxHeight) ||
+			(c.MaxVideoBitrate != 0 && s.BitrateValue() > float64(c.MaxVideoBitrate))
+
+		if transcode {
+			options = append(options, "-c:v", "libx264", "-preset:v", "veryfast", "-pix_fmt", "yuv420p")
+
+			if c.MaxHeight != 0 && s.Height > c.MaxHeight {
+				options = append(options, "-vf", fmt.Sprintf("scale=-2:%d", c.MaxHeight))
+			}
+
+			if c.MaxVideoBitrate != 0 {
+				options = append(options,
+					"-b:v", fmt.Sprintf("%dk", c.MaxVideoBitrate),
+					"-maxrate:v", fmt.Sprintf("%dk", c.MaxVideoBitrate),
+					"-bufsize:v", fmt.Sprintf("%dk", c.MaxVideoBitrate*2),
+				)
+			}
+
+			if c.KeyframeSeconds != 0 {
+				fps := s.FPSValue()
+				if fps == 0 {
+					fps = 25
+				}
+
+				gop := fmt.Sprintf("%.0f", fps*float64(c.KeyframeSeconds))
+				options = append(options, "-g", gop, "-keyint_min", gop, "-sc_threshold", "0")
+			}
+		} else {
+			options = append(options, "-c:v", "copy")
+		}
+	}
+
+	if audio := probe.AudioStreams(); len(audio) != 0 {
+		s := audio[0]
+		options = append(options, "-map", "0:a:0")
+
+		if len(c.AudioCodecs) != 0 && !contains(c.AudioCodecs, s.Codec) {
+			options = append(options, "-c:a", "aac", "-b:a", "128k", "-ar", "44100")
+		} else {
+			options = append(options, "-c:a", "copy")
+		}
+	}
+
+	return api.ProcessConfigIO{
+		ID:      id,
+		Address: d.Address(),
+		Options: append(options, d.muxer()...),
+	}
+}
+
+// EgressConfig returns a process config with the given ID and reference that pushes the input
+// to the destination. If a probe of the input is given, the streams are transcoded as required by
+// the constraints of the destination, otherwise they are copied.
+func (d Destination) EgressConfig(id, reference string, input api.ProcessConfigIO, probe *api.Probe) api.ProcessConfig {
+	output := d.Output("output_0")
+	if probe != nil {
+		output = d.TranscodingOutput("output_0", *probe)
+	}
+
+	return api.ProcessConfig{
+		ID:             id,
+		Type:           "ffmpeg",
+		Reference:      reference,
+		Options:        []string{"-err_detect", "ignore_err"},
+		Input:          []api.ProcessConfigIO{input},
+		Output:         []api.ProcessConfigIO{output},
+		Reconnect:      true,
+		ReconnectDelay: 15,
+		Autostart:      true,
+		StaleTimeout:   30,
+	}
+}
+
+// MaskConfig returns a copy of the config with the secrets of all destinations masked in the
+// addresses and options of the inputs and outputs. Use it before logging or diffing configs.
+func MaskConfig(config api.ProcessConfig, destinations ...Destination) api.ProcessConfig {
+	maskAll := func(s string) string {
+		for _, d := range destinations {
+			s = d.Mask(s)
+		}
+
+		return s
+	}
+
+	maskIO := func(ios []api.ProcessConfigIO) []api.ProcessConfigIO {
+		masked := make([]api.ProcessConfigIO, 0, len(ios))
+
+		for _, io := range ios {
+			io.Address = maskAll(io.Address)
+
+			options := make([]string, 0, len(io.Options))
+			for _, o := range io.Options {
+				options = append(options, maskAll(o))
+			}
+
+			io.Options = options
+			masked = append(masked, io)
+		}
+
+		return masked
+	}
+
+	config.Input = maskIO(config.Input)
+	config.Output = maskIO(config.Output)
+
+	return config
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
+}
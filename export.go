@@ -0,0 +1,337 @@
+package coreclient
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ArchiveVersion is the version of the archive format written by ExportProcesses.
+const ArchiveVersion = 1
+
+// ArchiveProcess is a process with its metadata in an archive.
+type ArchiveProcess struct {
+	Config   api.ProcessConfig       `json:"config"`
+	Metadata map[string]api.Metadata `json:"metadata,omitempty"`
+}
+
+// Archive is an export of processes and metadata of a core.
+type Archive struct {
+	Version   int                     `json:"version"`
+	CreatedAt int64                   `json:"created_at"`
+	CoreID    string                  `json:"core_id"`
+	Metadata  map[string]api.Metadata `json:"metadata,omitempty"`
+	Processes []ArchiveProcess        `json:"processes"`
+}
+
+// ExportOptions are the options for ExportProcesses.
+type ExportOptions struct {
+	// Select selects the processes to export. All processes are exported by default.
+	Select ProcessListOptions
+
+	// GlobalMetadata includes the global metadata in the archive.
+	GlobalMetadata bool
+}
+
+// ExportProcesses exports the config and the metadata of the selected processes and optionally
+// the global metadata into an archive.
+func ExportProcesses(client RestClient, opts ExportOptions) (Archive, error) {
+	archive := Archive{
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now().Unix(),
+		CoreID:    client.ID(),
+		Processes: []ArchiveProcess{},
+	}
+
+	list := opts.Select
+	list.Filter = []string{"config", "metadata"}
+
+	processes, err := client.ProcessList(list)
+	if err != nil {
+		return archive, err
+	}
+
+	for _, p := range processes {
+		if p.Config == nil {
+			continue
+		}
+
+		ap := ArchiveProcess{
+			Config: *p.Config,
+		}
+
+		if p.Metadata != nil {
+			if err := decodeMetadata(p.Metadata, &ap.Metadata); err != nil {
+				return archive, fmt.Errorf("invalid metadata of process %s: %w", p.ID, err)
+			}
+		}
+
+		archive.Processes = append(archive.Processes, ap)
+	}
+
+	if opts.GlobalMetadata {
+		m, err := client.Metadata("")
+		if err != nil {
+			return archive, err
+		}
+
+		if m != nil {
+			if err := decodeMetadata(m, &archive.Metadata); err != nil {
+				return archive, fmt.Errorf("invalid global metadata: %w", err)
+			}
+		}
+	}
+
+	return archive, nil
+}
+
+// WriteJSON writes the archive as a JSON document.
+func (a Archive) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+
+	return e.Encode(a)
+}
+
+// WriteTar writes the archive as a tar file. The tar file contains a "manifest.json" with the version
+// and the global metadata, and a "processes/{id}.json" for every process.
+func (a Archive) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	manifest := a
+	manifest.Processes = nil
+
+	if err := writeTarJSON(tw, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, p := range a.Processes {
+		if err := writeTarJSON(tw, "processes/"+p.Config.ID+".json", p); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeTarJSON(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+
+	return err
+}
+
+// ReadArchive reads an archive that has been written with WriteJSON or WriteTar.
+func ReadArchive(r io.Reader) (Archive, error) {
+	archive := Archive{}
+
+	br := bufio.NewReader(r)
+
+	first, err := br.Peek(1)
+	if err != nil {
+		return archive, err
+	}
+
+	if strings.ContainsRune(" \t\r\n{", rune(first[0])) {
+		if err := json.NewDecoder(br).Decode(&archive); err != nil {
+			return archive, err
+		}
+	} else {
+		tr := tar.NewReader(br)
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return archive, err
+			}
+
+			if hdr.Name == "manifest.json" {
+				processes := archive.Processes
+				if err := json.NewDecoder(tr).Decode(&archive); err != nil {
+					return archive, err
+				}
+				archive.Processes = processes
+			} else if strings.HasPrefix(hdr.Name, "processes/") && strings.HasSuffix(hdr.Name, ".json") {
+				p := ArchiveProcess{}
+				if err := json.NewDecoder(tr).Decode(&p); err != nil {
+					return archive, err
+				}
+				archive.Processes = append(archive.Processes, p)
+			}
+		}
+	}
+
+	if archive.Version == 0 || archive.Version > ArchiveVersion {
+		return archive, fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+
+	return archive, nil
+}
+
+// ImportConflict is the policy for processes that already exist on the core.
+type ImportConflict string
+
+const (
+	IMPORT_CONFLICT_SKIP      ImportConflict = "skip"
+	IMPORT_CONFLICT_OVERWRITE ImportConflict = "overwrite"
+	IMPORT_CONFLICT_RENAME    ImportConflict = "rename"
+)
+
+// ImportOptions are the options for ImportProcesses.
+type ImportOptions struct {
+	// RemapID maps the ID of a process in the archive to the ID on the core. Optional.
+	RemapID map[string]string
+
+	// Conflict is the policy for processes that already exist. Defaults to IMPORT_CONFLICT_SKIP.
+	Conflict ImportConflict
+
+	// Stopped imports the processes without starting them. Autostart is disabled in the
+	// imported configs, i.e. the stored flag differs from the archived one. Processes that
+	// are overwritten are stopped before they are updated.
+	Stopped bool
+
+	// GlobalMetadata imports the global metadata from the archive.
+	GlobalMetadata bool
+}
+
+// ImportResult is the result of importing a single process.
+type ImportResult struct {
+	ID     string // ID of the process in the archive
+	NewID  string // ID of the process on the core
+	Action string // "created", "overwritten", "renamed" or "skipped"
+	Err    error
+}
+
+// ImportProcesses recreates the processes and their metadata from the archive on the core. It
+// doesn't stop on the first failing process, but reports the result for every process. The
+// error is only non-nil if the existing processes or the global metadata couldn't be accessed.
+func ImportProcesses(client RestClient, archive Archive, opts ImportOptions) ([]ImportResult, error) {
+	results := []ImportResult{}
+
+	if len(opts.Conflict) == 0 {
+		opts.Conflict = IMPORT_CONFLICT_SKIP
+	}
+
+	existing, err := client.ProcessList(ProcessListOptions{Filter: []string{"state"}})
+	if err != nil {
+		return results, err
+	}
+
+	ids := map[string]struct{}{}
+	for _, p := range existing {
+		ids[p.ID] = struct{}{}
+	}
+
+	for _, p := range archive.Processes {
+		config := p.Config
+
+		result := ImportResult{
+			ID:     config.ID,
+			Action: "created",
+		}
+
+		if id, ok := opts.RemapID[config.ID]; ok {
+			config.ID = id
+		}
+
+		_, exists := ids[config.ID]
+
+		if exists {
+			switch opts.Conflict {
+			case IMPORT_CONFLICT_SKIP:
+				result.NewID = config.ID
+				result.Action = "skipped"
+				results = append(results, result)
+				continue
+			case IMPORT_CONFLICT_OVERWRITE:
+				result.Action = "overwritten"
+			case IMPORT_CONFLICT_RENAME:
+				result.Action = "renamed"
+				config.ID = uniqueID(config.ID, ids)
+				exists = false
+			default:
+				return results, fmt.Errorf("unknown conflict policy: %s", opts.Conflict)
+			}
+		}
+
+		result.NewID = config.ID
+
+		if opts.Stopped {
+			config.Autostart = false
+		}
+
+		if exists {
+			// The core restarts an updated process if it was running before
+			if opts.Stopped {
+				if err := client.ProcessCommand(config.ID, "stop"); err != nil {
+					result.Err = fmt.Errorf("stop: %w", err)
+				}
+			}
+
+			if result.Err == nil {
+				result.Err = client.ProcessUpdate(config.ID, config)
+			}
+		} else {
+			result.Err = client.ProcessAdd(config)
+		}
+
+		if result.Err == nil {
+			ids[config.ID] = struct{}{}
+		}
+
+		if result.Err == nil {
+			for key, m := range p.Metadata {
+				if err := client.ProcessMetadataSet(config.ID, key, m); err != nil {
+					result.Err = fmt.Errorf("metadata %s: %w", key, err)
+					break
+				}
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	if opts.GlobalMetadata {
+		for key, m := range archive.Metadata {
+			if err := client.MetadataSet(key, m); err != nil {
+				return results, fmt.Errorf("metadata %s: %w", key, err)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// uniqueID returns the ID with the lowest numeric suffix that is not yet in use.
+func uniqueID(id string, ids map[string]struct{}) string {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", id, i)
+		if _, ok := ids[candidate]; !ok {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Package filtergraph builds ffmpeg filter graphs for the -filter_complex option with correctly
+// escaped filter arguments and labeled pads.
+//
+// Example for scaling a video and overlaying a logo:
+//
+//	g := filtergraph.New()
+//	video := g.Apply([]filtergraph.Pad{filtergraph.Input(0, "v", 0)}, 1, filtergraph.Scale(1280, 720))
+//	out := g.Apply([]filtergraph.Pad{video[0], filtergraph.Input(1, "v", 0)}, 1, filtergraph.Overlay("W-w-10", "10"))
+//
+//	if err := g.Validate(skills); err != nil {
+//		...
+//	}
+//
+//	config.Options = append(config.Options, "-filter_complex", g.String())
+//	output.Options = append(output.Options, filtergraph.Map(out[0])...)
+package filtergraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// Pad is an input or output pad of a filter chain. It is either a stream of an input file,
+// e.g. "0:v:0", or a label of an output of another filter chain.
+type Pad string
+
+// Input returns the pad for a stream of an input file, e.g. Input(0, "v", 0) for "0:v:0".
+func Input(file int, streamType string, stream int) Pad {
+	return Pad(fmt.Sprintf("%d:%s:%d", file, streamType, stream))
+}
+
+func (p Pad) String() string {
+	return "[" + string(p) + "]"
+}
+
+// Map returns the output options to map the pad to an output.
+func Map(p Pad) []string {
+	return []string{"-map", p.String()}
+}
+
+// Arg is an argument of a filter. An argument without a key is passed positionally.
+type Arg struct {
+	Key   string
+	Value string
+}
+
+// Filter is a single ffmpeg filter with its arguments.
+type Filter struct {
+	Name string
+	Args []Arg
+}
+
+// NewFilter returns a filter with the given name and arguments.
+func NewFilter(name string, args ...Arg) Filter {
+	return Filter{
+		Name: name,
+		Args: args,
+	}
+}
+
+// With returns a copy of the filter with an additional argument.
+func (f Filter) With(key, value string) Filter {
+	f.Args = append(append([]Arg{}, f.Args...), Arg{Key: key, Value: value})
+	return f
+}
+
+// String returns the filter with its escaped arguments as it is used in a filter graph.
+func (f Filter) String() string {
+	if len(f.Args) == 0 {
+		return f.Name
+	}
+
+	args := make([]string, 0, len(f.Args))
+
+	for _, a := range f.Args {
+		v := escapeGraph(escapeOption(a.Value))
+		if len(a.Key) != 0 {
+			v = a.Key + "=" + v
+		}
+
+		args = append(args, v)
+	}
+
+	return f.Name + "=" + strings.Join(args, ":")
+}
+
+// escapeOption escapes a value on the level of the filter options.
+func escapeOption(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+	return r.Replace(s)
+}
+
+// escapeGraph escapes a filter description on the level of the filter graph.
+func escapeGraph(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`)
+	return r.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Scale scales the video to the given size. Use -1 or -2 for one of the dimensions to keep
+// the aspect ratio.
+func Scale(width, height int) Filter {
+	return NewFilter("scale", Arg{"w", strconv.Itoa(width)}, Arg{"h", strconv.Itoa(height)})
+}
+
+// FPS converts the video to the given frame rate.
+func FPS(fps float64) Filter {
+	return NewFilter("fps", Arg{"fps", formatFloat(fps)})
+}
+
+// Overlay overlays the second input on the first input at the given position. The
+// position can be an expression, e.g. "W-w-10".
+func Overlay(x, y string) Filter {
+	return NewFilter("overlay", Arg{"x", x}, Arg{"y", y})
+}
+
+// AResample resamples the audio to the given sample rate.
+func AResample(rate int) Filter {
+	return NewFilter("aresample", Arg{"", strconv.Itoa(rate)})
+}
+
+// Split splits the video into n outputs.
+func Split(n int) Filter {
+	return NewFilter("split", Arg{"", strconv.Itoa(n)})
+}
+
+// ASplit splits the audio into n outputs.
+func ASplit(n int) Filter {
+	return NewFilter("asplit", Arg{"", strconv.Itoa(n)})
+}
+
+// AMix mixes n audio inputs into one.
+func AMix(n int) Filter {
+	return NewFilter("amix", Arg{"inputs", strconv.Itoa(n)})
+}
+
+// DrawTextOptions are the options for the drawtext filter.
+type DrawTextOptions struct {
+	Text      string // the text, drawn as is without expansion
+	FontFile  string
+	FontSize  int
+	FontColor string // e.g. "white" or "0xffffff"
+	X, Y      string // position, can be an expression, e.g. "(w-text_w)/2"
+	Box       bool
+	BoxColor  string // e.g. "black@0.5"
+}
+
+// DrawText draws a text on the video.
+func DrawText(opts DrawTextOptions) Filter {
+	f := NewFilter("drawtext", Arg{"text", opts.Text}, Arg{"expansion", "none"})
+
+	if len(opts.FontFile) != 0 {
+		f = f.With("fontfile", opts.FontFile)
+	}
+
+	if opts.FontSize != 0 {
+		f = f.With("fontsize", strconv.Itoa(opts.FontSize))
+	}
+
+	if len(opts.FontColor) != 0 {
+		f = f.With("fontcolor", opts.FontColor)
+	}
+
+	if len(opts.X) != 0 {
+		f = f.With("x", opts.X)
+	}
+
+	if len(opts.Y) != 0 {
+		f = f.With("y", opts.Y)
+	}
+
+	if opts.Box {
+		f = f.With("box", "1")
+
+		if len(opts.BoxColor) != 0 {
+			f = f.With("boxcolor", opts.BoxColor)
+		}
+	}
+
+	return f
+}
+
+// chain is a list of filters with its input and output pads.
+type chain struct {
+	inputs  []Pad
+	filters []Filter
+	outputs []Pad
+}
+
+func (c chain) String() string {
+	var b strings.Builder
+
+	for _, p := range c.inputs {
+		b.WriteString(p.String())
+	}
+
+	filters := make([]string, 0, len(c.filters))
+	for _, f := range c.filters {
+		filters = append(filters, f.String())
+	}
+
+	b.WriteString(strings.Join(filters, ","))
+
+	for _, p := range c.outputs {
+		b.WriteString(p.String())
+	}
+
+	return b.String()
+}
+
+// Graph is a filter graph consisting of filter chains.
+type Graph struct {
+	chains []chain
+	labels int
+}
+
+// New returns a new empty filter graph.
+func New() *Graph {
+	return &Graph{}
+}
+
+// Apply adds a chain of filters to the graph. The inputs are connected to the first filter and
+// the last filter has n outputs. It returns the labeled output pads.
+func (g *Graph) Apply(inputs []Pad, n int, filters ...Filter) []Pad {
+	outputs := make([]Pad, 0, n)
+
+	for i := 0; i < n; i++ {
+		outputs = append(outputs, Pad(fmt.Sprintf("f%d", g.labels)))
+		g.labels++
+	}
+
+	g.chains = append(g.chains, chain{
+		inputs:  append([]Pad{}, inputs...),
+		filters: append([]Filter{}, filters...),
+		outputs: outputs,
+	})
+
+	return outputs
+}
+
+// Split adds a split (or asplit for audio pads) of the pad into n outputs.
+func (g *Graph) Split(pad Pad, audio bool, n int) []Pad {
+	if audio {
+		return g.Apply([]Pad{pad}, n, ASplit(n))
+	}
+
+	return g.Apply([]Pad{pad}, n, Split(n))
+}
+
+// String returns the filter graph for the -filter_complex option.
+func (g *Graph) String() string {
+	chains := make([]string, 0, len(g.chains))
+
+	for _, c := range g.chains {
+		chains = append(chains, c.String())
+	}
+
+	return strings.Join(chains, ";")
+}
+
+// Filters returns the sorted names of all filters used in the graph.
+func (g *Graph) Filters() []string {
+	names := map[string]struct{}{}
+
+	for _, c := range g.chains {
+		for _, f := range c.filters {
+			names[f.Name] = struct{}{}
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+// Validate checks that all filters used in the graph are available according to the skills
+// of the core.
+func (g *Graph) Validate(skills api.Skills) error {
+	available := map[string]struct{}{}
+	for _, f := range skills.Filters {
+		available[f.ID] = struct{}{}
+	}
+
+	missing := []string{}
+	for _, name := range g.Filters() {
+		if _, ok := available[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("filters not available: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
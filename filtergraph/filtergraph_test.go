@@ -0,0 +1,104 @@
+package filtergraph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+func TestFilterEscaping(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{
+			// The example from the "Notes on filtergraph escaping" in the ffmpeg filter documentation
+			name:   "ffmpeg docs",
+			filter: NewFilter("drawtext", Arg{"text", "this is a 'string': may contain one, or more, special characters"}),
+			want:   `drawtext=text=this is a \\\'string\\\'\\: may contain one\, or more\, special characters`,
+		},
+		{
+			name:   "backslash and graph separators",
+			filter: NewFilter("drawtext", Arg{"text", `a\b;[c]`}),
+			want:   `drawtext=text=a\\\\b\;\[c\]`,
+		},
+		{
+			name:   "positional and keyed arguments",
+			filter: NewFilter("overlay", Arg{"", "W-w-10"}, Arg{"y", "10"}),
+			want:   `overlay=W-w-10:y=10`,
+		},
+		{
+			name:   "no arguments",
+			filter: NewFilter("null"),
+			want:   `null`,
+		},
+		{
+			name:   "drawtext",
+			filter: DrawText(DrawTextOptions{Text: "Live: 100%", FontSize: 24, X: "(w-text_w)/2", Box: true, BoxColor: "black@0.5"}),
+			want:   `drawtext=text=Live\\: 100%:expansion=none:fontsize=24:x=(w-text_w)/2:box=1:boxcolor=black@0.5`,
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.filter.String(); got != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGraph(t *testing.T) {
+	g := New()
+
+	video := g.Apply([]Pad{Input(0, "v", 0)}, 1, Scale(1280, 720), FPS(29.97))
+	videos := g.Split(video[0], false, 2)
+	audios := g.Split(Input(0, "a", 0), true, 2)
+	out := g.Apply([]Pad{videos[1], Input(1, "v", 0)}, 1, Overlay("W-w-10", "10"))
+
+	want := "[0:v:0]scale=w=1280:h=720,fps=fps=29.97[f0];" +
+		"[f0]split=2[f1][f2];" +
+		"[0:a:0]asplit=2[f3][f4];" +
+		"[f2][1:v:0]overlay=x=W-w-10:y=10[f5]"
+
+	if got := g.String(); got != want {
+		t.Errorf("graph:\n got %s\nwant %s", got, want)
+	}
+
+	if !reflect.DeepEqual(videos, []Pad{"f1", "f2"}) || !reflect.DeepEqual(audios, []Pad{"f3", "f4"}) {
+		t.Errorf("unexpected split outputs: %v %v", videos, audios)
+	}
+
+	if got := Map(out[0]); !reflect.DeepEqual(got, []string{"-map", "[f5]"}) {
+		t.Errorf("unexpected map: %v", got)
+	}
+
+	if got := g.Filters(); !reflect.DeepEqual(got, []string{"asplit", "fps", "overlay", "scale", "split"}) {
+		t.Errorf("unexpected filters: %v", got)
+	}
+}
+
+func TestGraphValidate(t *testing.T) {
+	g := New()
+	video := g.Apply([]Pad{Input(0, "v", 0)}, 1, Scale(1280, 720))
+	g.Apply([]Pad{video[0], Input(1, "v", 0)}, 1, Overlay("0", "0"))
+
+	skills := api.Skills{
+		Filters: []api.SkillsFilter{{ID: "scale"}, {ID: "overlay"}},
+	}
+
+	if err := g.Validate(skills); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	g.Split(Input(0, "a", 0), true, 2)
+
+	err := g.Validate(skills)
+	if err == nil {
+		t.Fatalf("expected an error for the missing filter")
+	}
+
+	if want := "filters not available: asplit"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err.Error(), want)
+	}
+}
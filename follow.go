@@ -0,0 +1,202 @@
+package coreclient
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ProcessLogLine is a line of the log of a process as delivered by FollowProcessLog.
+type ProcessLogLine struct {
+	ProcessLogEntry
+
+	// RunStart is the start of the run of the process the line belongs to.
+	RunStart time.Time
+
+	// NewRun is true for the first line of a run that started while following the log.
+	NewRun bool
+}
+
+// FollowOptions are the options for FollowProcessLog.
+type FollowOptions struct {
+	// Interval is the time between two polls of the report. Defaults to 1 second.
+	Interval time.Duration
+
+	// OnlyNew skips the lines that are already in the log of the current run.
+	OnlyNew bool
+}
+
+// logCursor keeps track of the lines that have been delivered for a run. Because the timestamps
+// of the log have a resolution of one second, it counts how often each line has been delivered
+// with the most recent timestamp, such that repeated identical lines are not lost.
+type logCursor struct {
+	createdAt int64
+	lastTime  string
+	atLast    map[string]int
+}
+
+// next returns the lines of the log that haven't been delivered yet and advances the cursor.
+func (c *logCursor) next(log [][2]string) [][2]string {
+	lines := [][2]string{}
+
+	// seen counts the occurrences of each line with the most recent timestamp in this log
+	seen := map[string]int{}
+
+	for _, l := range log {
+		if len(c.lastTime) != 0 && compareLogTime(l[0], c.lastTime) < 0 {
+			continue
+		}
+
+		if l[0] != c.lastTime {
+			c.lastTime = l[0]
+			c.atLast = map[string]int{}
+			seen = map[string]int{}
+		}
+
+		seen[l[1]]++
+		if seen[l[1]] <= c.atLast[l[1]] {
+			continue
+		}
+
+		c.atLast[l[1]]++
+		lines = append(lines, l)
+	}
+
+	return lines
+}
+
+func compareLogTime(a, b string) int {
+	ta, tb := parseLogTime(a), parseLogTime(b)
+
+	if ta.Before(tb) {
+		return -1
+	} else if ta.After(tb) {
+		return 1
+	}
+
+	return 0
+}
+
+// FollowProcessLog polls the report of the process with the given ID and delivers new log lines in
+// order over the returned channel, like "tail -f". If a new run starts, the remaining lines of the
+// previous run are taken from the history before the lines of the new run are delivered. Errors while
+// fetching the report are ignored. The channel will be closed when the context is done.
+func FollowProcessLog(ctx context.Context, client RestClient, id string, opts FollowOptions) <-chan ProcessLogLine {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	ch := make(chan ProcessLogLine)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		var cursor *logCursor
+
+		send := func(createdAt int64, lines [][2]string, newRun bool) bool {
+			for i, l := range lines {
+				line := ProcessLogLine{
+					ProcessLogEntry: ParseProcessLogLine(l),
+					RunStart:        time.Unix(createdAt, 0),
+					NewRun:          newRun && i == 0,
+				}
+
+				select {
+				case ch <- line:
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			return true
+		}
+
+		for {
+			report, err := client.ProcessReport(id)
+			if err == nil {
+				if cursor == nil {
+					cursor = &logCursor{createdAt: report.CreatedAt}
+					lines := cursor.next(report.Log)
+					if !opts.OnlyNew && !send(report.CreatedAt, lines, false) {
+						return
+					}
+				} else {
+					if report.CreatedAt != cursor.createdAt {
+						if h := findHistoryEntry(report.History, cursor.createdAt); h != nil {
+							if !send(cursor.createdAt, cursor.next(h.Log), false) {
+								return
+							}
+						}
+
+						cursor = &logCursor{createdAt: report.CreatedAt}
+
+						if !send(report.CreatedAt, cursor.next(report.Log), true) {
+							return
+						}
+					} else if !send(report.CreatedAt, cursor.next(report.Log), false) {
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return ch
+}
+
+func findHistoryEntry(history []api.ProcessReportHistoryEntry, createdAt int64) *api.ProcessReportHistoryEntry {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].CreatedAt == createdAt {
+			return &history[i]
+		}
+	}
+
+	return nil
+}
+
+// FollowProcessLogReader is like FollowProcessLog, but returns the raw log lines, each terminated
+// by a newline, as a reader. Close the reader or cancel the context to stop following.
+func FollowProcessLogReader(ctx context.Context, client RestClient, id string, opts FollowOptions) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+
+	r, w := io.Pipe()
+
+	go func() {
+		defer cancel()
+
+		for line := range FollowProcessLog(ctx, client, id, opts) {
+			if _, err := io.WriteString(w, line.Line+"\n"); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+
+		w.CloseWithError(ctx.Err())
+	}()
+
+	return &followReader{
+		PipeReader: r,
+		cancel:     cancel,
+	}
+}
+
+type followReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *followReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
@@ -0,0 +1,153 @@
+package coreclient
+
+import (
+	"fmt"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// HealthStatus is the overall health of a process.
+type HealthStatus string
+
+const (
+	HEALTH_HEALTHY   HealthStatus = "healthy"
+	HEALTH_DEGRADED  HealthStatus = "degraded"
+	HEALTH_UNHEALTHY HealthStatus = "unhealthy"
+)
+
+// HealthReason is a single finding of the health evaluation.
+type HealthReason struct {
+	Check   string       // name of the check, e.g. "speed"
+	Status  HealthStatus // HEALTH_DEGRADED or HEALTH_UNHEALTHY
+	Message string
+}
+
+// Health is the verdict of the health evaluation of a process.
+type Health struct {
+	Status  HealthStatus
+	Reasons []HealthReason
+}
+
+// HealthThresholds are the thresholds for the health evaluation. A zero value
+// selects the default.
+type HealthThresholds struct {
+	// MinSpeed is the min. speed of the process. The process is degraded if the speed of
+	// all samples is below this value. Defaults to 1.0.
+	MinSpeed float64
+
+	// MaxDrop and MaxDup are the max. number of frames that may be dropped or duplicated between
+	// the first and the last sample before the process is degraded. Defaults to 0.
+	MaxDrop uint64
+	MaxDup  uint64
+
+	// LimitRatio is the fraction of the CPU and memory limits of the process that may be used
+	// before the process is degraded. Defaults to 1.0.
+	LimitRatio float64
+}
+
+func (t HealthThresholds) withDefaults() HealthThresholds {
+	if t.MinSpeed <= 0 {
+		t.MinSpeed = 1.0
+	}
+
+	if t.LimitRatio <= 0 {
+		t.LimitRatio = 1.0
+	}
+
+	return t
+}
+
+// EvaluateProcessHealth evaluates the health of a process from consecutive samples of its state,
+// ordered from the oldest to the most recent one. At least two samples are required to detect that
+// the process doesn't advance. The limits are the limits from the config of the process.
+func EvaluateProcessHealth(samples []api.ProcessState, limits api.ProcessConfigLimits, thresholds HealthThresholds) Health {
+	thresholds = thresholds.withDefaults()
+
+	health := Health{
+		Status:  HEALTH_HEALTHY,
+		Reasons: []HealthReason{},
+	}
+
+	add := func(check string, status HealthStatus, format string, args ...interface{}) {
+		health.Reasons = append(health.Reasons, HealthReason{
+			Check:   check,
+			Status:  status,
+			Message: fmt.Sprintf(format, args...),
+		})
+
+		if status == HEALTH_UNHEALTHY || health.Status == HEALTH_HEALTHY {
+			health.Status = status
+		}
+	}
+
+	if len(samples) == 0 {
+		add("state", HEALTH_UNHEALTHY, "no samples available")
+		return health
+	}
+
+	last := samples[len(samples)-1]
+
+	if last.State != "running" {
+		add("state", HEALTH_UNHEALTHY, "process is %s", last.State)
+		return health
+	}
+
+	if last.Progress == nil {
+		add("progress", HEALTH_UNHEALTHY, "no progress available")
+		return health
+	}
+
+	slow := true
+	for _, s := range samples {
+		if s.Progress == nil || s.Progress.Speed >= thresholds.MinSpeed {
+			slow = false
+			break
+		}
+	}
+
+	if slow {
+		add("speed", HEALTH_DEGRADED, "speed %.2f is below %.2f", last.Progress.Speed, thresholds.MinSpeed)
+	}
+
+	if len(samples) > 1 {
+		first := samples[0]
+
+		// A restart in between the samples resets the counters
+		if first.Progress != nil && first.State == "running" && first.Runtime <= last.Runtime {
+			if last.Progress.Frame == first.Progress.Frame && last.Progress.Packet == first.Progress.Packet {
+				add("frames", HEALTH_UNHEALTHY, "frame counter is not advancing (%d)", last.Progress.Frame)
+			}
+
+			if drop := last.Progress.Drop - first.Progress.Drop; last.Progress.Drop >= first.Progress.Drop && drop > thresholds.MaxDrop {
+				add("drop", HEALTH_DEGRADED, "%d frames dropped", drop)
+			}
+
+			if dup := last.Progress.Dup - first.Progress.Dup; last.Progress.Dup >= first.Progress.Dup && dup > thresholds.MaxDup {
+				add("dup", HEALTH_DEGRADED, "%d frames duplicated", dup)
+			}
+		}
+	}
+
+	for _, input := range last.Progress.Input {
+		if input.Bitrate == 0 {
+			add("input_bitrate", HEALTH_UNHEALTHY, "input %s (%s) has a bitrate of zero", input.ID, input.Type)
+		}
+
+		if input.AVstream != nil && input.AVstream.Input.State == "idle" {
+			add("avstream", HEALTH_DEGRADED, "avstream input %s (%s) is idle", input.ID, input.Type)
+		}
+	}
+
+	if limits.CPU > 0 && last.CPU > limits.CPU*thresholds.LimitRatio {
+		add("cpu", HEALTH_DEGRADED, "cpu usage %.1f%% is above %.1f%%", last.CPU, limits.CPU*thresholds.LimitRatio)
+	}
+
+	if limits.Memory > 0 {
+		max := float64(limits.Memory*1024*1024) * thresholds.LimitRatio
+		if float64(last.Memory) > max {
+			add("memory", HEALTH_DEGRADED, "memory usage %d bytes is above %.0f bytes", last.Memory, max)
+		}
+	}
+
+	return health
+}
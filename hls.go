@@ -0,0 +1,271 @@
+package coreclient
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// HLSPreset describes an HLS output of a process.
+type HLSPreset struct {
+	// Filesystem is the filesystem the playlist and the segments are written to, "mem" or "disk".
+	// Defaults to "mem".
+	Filesystem string
+
+	// Name is the path of the playlist without the extension, e.g. "/channel". The segments
+	// will be named "{name}_seg_{number}.{ext}".
+	Name string
+
+	// SegmentDuration is the target duration of a segment in seconds. Defaults to 2 seconds.
+	SegmentDuration uint
+
+	// ListSize is the number of segments in the playlist. Defaults to 6.
+	ListSize uint
+
+	// LowLatency writes fragmented MP4 segments with independent segments, suitable for low latency HLS.
+	LowLatency bool
+
+	// MaxFileAge is the max. age of a segment in seconds before it is removed by the cleanup.
+	// Defaults to 3 times the duration of the playlist.
+	MaxFileAge uint
+}
+
+func (p HLSPreset) withDefaults() HLSPreset {
+	if len(p.Filesystem) == 0 {
+		p.Filesystem = "mem"
+	}
+
+	if p.SegmentDuration == 0 {
+		p.SegmentDuration = 2
+	}
+
+	if p.ListSize == 0 {
+		p.ListSize = 6
+	}
+
+	if p.MaxFileAge == 0 {
+		p.MaxFileAge = 3 * p.SegmentDuration * p.ListSize
+	}
+
+	p.Name = path.Clean("/" + p.Name)
+
+	return p
+}
+
+func (p HLSPreset) segmentExt() string {
+	if p.LowLatency {
+		return "mp4"
+	}
+
+	return "ts"
+}
+
+// Output returns the output for the process config with the ffmpeg options for the HLS
+// muxer and the matching cleanup rules.
+func (p HLSPreset) Output(id string) (api.ProcessConfigIO, error) {
+	p = p.withDefaults()
+
+	placeholder, ok := filesystemPlaceholders[p.Filesystem]
+	if !ok {
+		return api.ProcessConfigIO{}, fmt.Errorf("unknown filesystem: %s", p.Filesystem)
+	}
+
+	ext := p.segmentExt()
+	flags := "append_list+delete_segments+program_date_time+temp_file"
+
+	options := []string{
+		"-f", "hls",
+		"-start_number", "0",
+		"-hls_time", strconv.FormatUint(uint64(p.SegmentDuration), 10),
+		"-hls_list_size", strconv.FormatUint(uint64(p.ListSize), 10),
+		"-hls_delete_threshold", "4",
+	}
+
+	if p.LowLatency {
+		flags += "+independent_segments"
+		options = append(options,
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", path.Base(p.Name)+"_init.mp4",
+		)
+	} else {
+		options = append(options, "-hls_segment_type", "mpegts")
+	}
+
+	options = append(options,
+		"-hls_flags", flags,
+		"-hls_segment_filename", placeholder+p.Name+"_seg_%04d."+ext,
+		"-master_pl_name", path.Base(p.Name)+".m3u8",
+		"-master_pl_publish_rate", "2",
+	)
+
+	output := api.ProcessConfigIO{
+		ID:      id,
+		Address: placeholder + p.Name + "_output_0.m3u8",
+		Options: options,
+		Cleanup: p.Cleanup(),
+	}
+
+	return output, nil
+}
+
+// Cleanup returns the cleanup rules for the files written by the preset.
+func (p HLSPreset) Cleanup() []api.ProcessConfigIOCleanup {
+	p = p.withDefaults()
+
+	prefix := p.Filesystem + "fs:" + p.Name
+
+	cleanup := []api.ProcessConfigIOCleanup{
+		{
+			Pattern:       prefix + "_seg_*." + p.segmentExt(),
+			MaxFiles:      p.ListSize + 6,
+			MaxFileAge:    p.MaxFileAge,
+			PurgeOnDelete: true,
+		},
+		{
+			Pattern:       prefix + ".m3u8",
+			MaxFileAge:    p.MaxFileAge,
+			PurgeOnDelete: true,
+		},
+		{
+			Pattern:       prefix + "_*.m3u8",
+			MaxFileAge:    p.MaxFileAge,
+			PurgeOnDelete: true,
+		},
+	}
+
+	if p.LowLatency {
+		cleanup = append(cleanup, api.ProcessConfigIOCleanup{
+			Pattern:       prefix + "_init.mp4",
+			PurgeOnDelete: true,
+		})
+	}
+
+	return cleanup
+}
+
+var filesystemPlaceholders = map[string]string{
+	"mem":  "{memfs}",
+	"disk": "{diskfs}",
+}
+
+var (
+	ffmpegSequenceRegex = regexp.MustCompile(`%0?\d*d`)
+	ffmpegVariantRegex  = regexp.MustCompile(`%v`)
+)
+
+// exampleFilename returns a filename as ffmpeg would produce it for the given pattern.
+func exampleFilename(pattern string) string {
+	pattern = ffmpegSequenceRegex.ReplaceAllString(pattern, "0001")
+	return ffmpegVariantRegex.ReplaceAllString(pattern, "0")
+}
+
+// ValidateHLSCleanup checks that the cleanup rules of the HLS outputs of a process config match the
+// files the outputs produce, that the files on the mem filesystem are bounded by a max. number
+// of files or a max. age, and that the init segment of fMP4 outputs isn't removed while the output
+// is running. It returns a list of problems, which is empty if all outputs are fine.
+func ValidateHLSCleanup(config api.ProcessConfig) []string {
+	problems := []string{}
+
+	for _, output := range config.Output {
+		if !isHLSOutput(output) {
+			continue
+		}
+
+		files := map[string]string{
+			"playlist": exampleFilename(output.Address),
+		}
+
+		fmp4 := false
+
+		for i := 0; i < len(output.Options)-1; i++ {
+			switch output.Options[i] {
+			case "-hls_segment_filename":
+				files["segment"] = exampleFilename(output.Options[i+1])
+			case "-master_pl_name":
+				files["master playlist"] = path.Join(path.Dir(output.Address), output.Options[i+1])
+			case "-hls_segment_type":
+				fmp4 = output.Options[i+1] == "fmp4"
+			case "-hls_fmp4_init_filename":
+				files["init segment"] = path.Join(path.Dir(output.Address), output.Options[i+1])
+			}
+		}
+
+		if _, ok := files["init segment"]; fmp4 && !ok {
+			files["init segment"] = path.Join(path.Dir(output.Address), "init.mp4")
+		}
+
+		if _, ok := files["segment"]; !ok {
+			ext := path.Ext(output.Address)
+			files["segment"] = strings.TrimSuffix(output.Address, ext) + "0.ts"
+		}
+
+		for _, kind := range []string{"playlist", "master playlist", "segment", "init segment"} {
+			file, ok := files[kind]
+			if !ok {
+				continue
+			}
+
+			pattern, ok := placeholderToCleanup(file)
+			if !ok {
+				continue
+			}
+
+			rule := matchingCleanup(output.Cleanup, pattern)
+			if rule == nil {
+				problems = append(problems, fmt.Sprintf("output %s: no cleanup rule matches the %s %s", output.ID, kind, file))
+				continue
+			}
+
+			if kind == "init segment" {
+				// The init segment is written once and must not be removed before the process is deleted
+				for _, c := range output.Cleanup {
+					if match, _ := path.Match(c.Pattern, pattern); match && (c.MaxFiles != 0 || c.MaxFileAge != 0) {
+						problems = append(problems, fmt.Sprintf("output %s: the cleanup rule %s removes the %s %s", output.ID, c.Pattern, kind, file))
+					}
+				}
+			}
+
+			if kind == "segment" && strings.HasPrefix(pattern, "memfs:") && rule.MaxFiles == 0 && rule.MaxFileAge == 0 {
+				problems = append(problems, fmt.Sprintf("output %s: the cleanup rule %s doesn't limit the number or the age of the segments", output.ID, rule.Pattern))
+			}
+		}
+	}
+
+	return problems
+}
+
+func isHLSOutput(output api.ProcessConfigIO) bool {
+	for i := 0; i < len(output.Options)-1; i++ {
+		if output.Options[i] == "-f" && output.Options[i+1] == "hls" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// placeholderToCleanup converts an address with a filesystem placeholder to the
+// notation used in cleanup patterns, e.g. "{memfs}/foo.m3u8" to "memfs:/foo.m3u8".
+func placeholderToCleanup(address string) (string, bool) {
+	for fs, placeholder := range filesystemPlaceholders {
+		if strings.HasPrefix(address, placeholder) {
+			return fs + "fs:" + path.Clean("/"+strings.TrimPrefix(address, placeholder)), true
+		}
+	}
+
+	return "", false
+}
+
+func matchingCleanup(cleanup []api.ProcessConfigIOCleanup, file string) *api.ProcessConfigIOCleanup {
+	for i, c := range cleanup {
+		if match, _ := path.Match(c.Pattern, file); match {
+			return &cleanup[i]
+		}
+	}
+
+	return nil
+}
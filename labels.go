@@ -0,0 +1,287 @@
+package coreclient
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// LabelsMetadataKey is the process metadata key where the labels of a process are stored
+// as a JSON object with string values.
+const LabelsMetadataKey = "labels"
+
+var labelKeyRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-\.]*[a-zA-Z0-9])?/)?[a-zA-Z0-9]([a-zA-Z0-9_\-\.]*[a-zA-Z0-9])?$`)
+
+// ProcessLabels returns the labels of the process with the given ID.
+func ProcessLabels(client RestClient, id string) (map[string]string, error) {
+	m, err := client.ProcessMetadata(id, LabelsMetadataKey)
+	if err != nil {
+		if isNotFound(err) {
+			return map[string]string{}, nil
+		}
+
+		return nil, err
+	}
+
+	return labelsFromMetadata(m)
+}
+
+// ProcessLabelsSet adds the labels to the process with the given ID. Existing labels
+// with the same key are replaced.
+func ProcessLabelsSet(client RestClient, id string, labels map[string]string) error {
+	for key := range labels {
+		if !labelKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid label key: %s", key)
+		}
+	}
+
+	current, err := ProcessLabels(client, id)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range labels {
+		current[key] = value
+	}
+
+	return client.ProcessMetadataSet(id, LabelsMetadataKey, current)
+}
+
+// ProcessLabelsRemove removes the labels with the given keys from the process with the given ID.
+func ProcessLabelsRemove(client RestClient, id string, keys ...string) error {
+	current, err := ProcessLabels(client, id)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(current, key)
+	}
+
+	return client.ProcessMetadataSet(id, LabelsMetadataKey, current)
+}
+
+// ProcessListByLabels returns the processes selected by opts whose labels match the label selector,
+// e.g. "env=prod,team in (news,sports),!deprecated". First, only the metadata of the processes is
+// fetched in order to find the matching processes. Then the matching processes are fetched with the
+// filter from opts, unless the filter only asks for the metadata.
+func ProcessListByLabels(client RestClient, opts ProcessListOptions, selector string) ([]api.Process, error) {
+	s, err := ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	list := opts
+	list.Filter = []string{"metadata"}
+
+	processes, err := client.ProcessList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []api.Process{}
+	ids := []string{}
+
+	for _, p := range processes {
+		if s.Matches(labelsOfProcess(p)) {
+			matches = append(matches, p)
+			ids = append(ids, p.ID)
+		}
+	}
+
+	if len(matches) == 0 || (len(opts.Filter) == 1 && opts.Filter[0] == "metadata") {
+		return matches, nil
+	}
+
+	list = opts
+	list.ID = ids
+
+	return client.ProcessList(list)
+}
+
+func labelsOfProcess(p api.Process) map[string]string {
+	m, ok := p.Metadata.(map[string]interface{})
+	if !ok {
+		return map[string]string{}
+	}
+
+	labels, err := labelsFromMetadata(m[LabelsMetadataKey])
+	if err != nil {
+		return map[string]string{}
+	}
+
+	return labels
+}
+
+func labelsFromMetadata(m api.Metadata) (map[string]string, error) {
+	labels := map[string]string{}
+
+	if m == nil {
+		return labels, nil
+	}
+
+	if err := decodeMetadata(m, &labels); err != nil {
+		return nil, fmt.Errorf("invalid labels: %w", err)
+	}
+
+	return labels, nil
+}
+
+// labelRequirement is a single requirement of a label selector.
+type labelRequirement struct {
+	key    string
+	op     string // "exists", "!exists", "=", "!=", "in", "notin"
+	values []string
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+
+	switch r.op {
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	case "=":
+		return ok && value == r.values[0]
+	case "!=":
+		return !ok || value != r.values[0]
+	case "in":
+		if !ok {
+			return false
+		}
+
+		for _, v := range r.values {
+			if v == value {
+				return true
+			}
+		}
+
+		return false
+	case "notin":
+		for _, v := range r.values {
+			if ok && v == value {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (r labelRequirement) String() string {
+	switch r.op {
+	case "exists":
+		return r.key
+	case "!exists":
+		return "!" + r.key
+	case "=", "!=":
+		return r.key + r.op + r.values[0]
+	}
+
+	return r.key + " " + r.op + " (" + strings.Join(r.values, ",") + ")"
+}
+
+// LabelSelector selects processes by their labels, similar to Kubernetes label selectors.
+// All requirements of the selector must match.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+var labelSetRegex = regexp.MustCompile(`^(\S+)\s+(in|notin)\s+\(([^)]*)\)$`)
+
+// ParseLabelSelector parses a comma separated list of requirements. Supported requirements are
+// "key" (label exists), "!key" (label doesn't exist), "key=value", "key==value", "key!=value",
+// "key in (v1,v2)" and "key notin (v1,v2)". An empty selector matches everything.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	s := LabelSelector{}
+
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		r := labelRequirement{}
+
+		if m := labelSetRegex.FindStringSubmatch(part); m != nil {
+			r.key = m[1]
+			r.op = m[2]
+
+			for _, v := range strings.Split(m[3], ",") {
+				if v = strings.TrimSpace(v); len(v) != 0 {
+					r.values = append(r.values, v)
+				}
+			}
+
+			sort.Strings(r.values)
+		} else if key, value, found := strings.Cut(part, "!="); found {
+			r.key, r.op, r.values = strings.TrimSpace(key), "!=", []string{strings.TrimSpace(value)}
+		} else if key, value, found := strings.Cut(part, "=="); found {
+			r.key, r.op, r.values = strings.TrimSpace(key), "=", []string{strings.TrimSpace(value)}
+		} else if key, value, found := strings.Cut(part, "="); found {
+			r.key, r.op, r.values = strings.TrimSpace(key), "=", []string{strings.TrimSpace(value)}
+		} else if strings.HasPrefix(part, "!") {
+			r.key, r.op = strings.TrimSpace(part[1:]), "!exists"
+		} else {
+			r.key, r.op = part, "exists"
+		}
+
+		if !labelKeyRegex.MatchString(r.key) {
+			return s, fmt.Errorf("invalid label key in requirement %q", part)
+		}
+
+		s.requirements = append(s.requirements, r)
+	}
+
+	return s, nil
+}
+
+// splitSelector splits the selector at the commas that are not within parentheses.
+func splitSelector(selector string) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, selector[start:])
+}
+
+// Matches returns whether the labels match all requirements of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s LabelSelector) String() string {
+	parts := make([]string, 0, len(s.requirements))
+
+	for _, r := range s.requirements {
+		parts = append(parts, r.String())
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,121 @@
+package coreclient
+
+import (
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		parsed   string
+		matches  []map[string]string
+		misses   []map[string]string
+	}{
+		{
+			selector: "",
+			parsed:   "",
+			matches:  []map[string]string{{}, {"env": "prod"}},
+		},
+		{
+			selector: "env",
+			parsed:   "env",
+			matches:  []map[string]string{{"env": ""}, {"env": "prod"}},
+			misses:   []map[string]string{{}, {"team": "news"}},
+		},
+		{
+			selector: "!deprecated",
+			parsed:   "!deprecated",
+			matches:  []map[string]string{{}, {"env": "prod"}},
+			misses:   []map[string]string{{"deprecated": "true"}},
+		},
+		{
+			selector: "env=prod",
+			parsed:   "env=prod",
+			matches:  []map[string]string{{"env": "prod"}},
+			misses:   []map[string]string{{}, {"env": "dev"}},
+		},
+		{
+			selector: "env == prod",
+			parsed:   "env=prod",
+			matches:  []map[string]string{{"env": "prod"}},
+			misses:   []map[string]string{{"env": "dev"}},
+		},
+		{
+			selector: "env!=prod",
+			parsed:   "env!=prod",
+			matches:  []map[string]string{{}, {"env": "dev"}},
+			misses:   []map[string]string{{"env": "prod"}},
+		},
+		{
+			selector: "team in (sports, news)",
+			parsed:   "team in (news,sports)",
+			matches:  []map[string]string{{"team": "news"}, {"team": "sports"}},
+			misses:   []map[string]string{{}, {"team": "weather"}},
+		},
+		{
+			selector: "team notin (sports,news)",
+			parsed:   "team notin (news,sports)",
+			matches:  []map[string]string{{}, {"team": "weather"}},
+			misses:   []map[string]string{{"team": "news"}},
+		},
+		{
+			selector: "env=prod, team in (news,sports), !deprecated",
+			parsed:   "env=prod,team in (news,sports),!deprecated",
+			matches:  []map[string]string{{"env": "prod", "team": "news"}},
+			misses: []map[string]string{
+				{"env": "prod", "team": "weather"},
+				{"env": "dev", "team": "news"},
+				{"env": "prod", "team": "news", "deprecated": "yes"},
+			},
+		},
+		{
+			selector: "example.com/tier=gold",
+			parsed:   "example.com/tier=gold",
+			matches:  []map[string]string{{"example.com/tier": "gold"}},
+			misses:   []map[string]string{{"tier": "gold"}},
+		},
+	}
+
+	for _, test := range tests {
+		s, err := ParseLabelSelector(test.selector)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.selector, err)
+			continue
+		}
+
+		if s.String() != test.parsed {
+			t.Errorf("%q: parsed as %q, want %q", test.selector, s.String(), test.parsed)
+		}
+
+		for _, labels := range test.matches {
+			if !s.Matches(labels) {
+				t.Errorf("%q: expected a match for %v", test.selector, labels)
+			}
+		}
+
+		for _, labels := range test.misses {
+			if s.Matches(labels) {
+				t.Errorf("%q: expected no match for %v", test.selector, labels)
+			}
+		}
+	}
+}
+
+func TestParseLabelSelectorErrors(t *testing.T) {
+	selectors := []string{
+		"-env=prod",
+		"env-=prod",
+		"my env=prod",
+		"=prod",
+		"!",
+		"team in (news,sports",
+		"team notin news",
+		"a/b/c=x",
+	}
+
+	for _, selector := range selectors {
+		if _, err := ParseLabelSelector(selector); err == nil {
+			t.Errorf("%q: expected an error", selector)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package coreclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// MigrateFile is a file on a filesystem of the source core that will be copied to the target core.
+type MigrateFile struct {
+	Filesystem string
+	Path       string
+}
+
+// MigrateOptions are the options for MigrateProcesses.
+type MigrateOptions struct {
+	// Select selects the processes on the source core to migrate. An empty selection is
+	// rejected, unless All is set.
+	Select ProcessListOptions
+
+	// All allows an empty selection, i.e. migrates all processes.
+	All bool
+
+	// Files are additional files that will be copied to the target core.
+	Files []MigrateFile
+
+	// CopyReferencedFiles copies the files on the disk filesystem that are referenced with the
+	// {diskfs} placeholder in the inputs of the processes.
+	CopyReferencedFiles bool
+
+	// StartTimeout is the max. time to wait for the processes to run on the target core.
+	// Defaults to 1 minute.
+	StartTimeout time.Duration
+
+	// KeepSource only stops the processes on the source core instead of deleting them.
+	KeepSource bool
+}
+
+// MigrateReport is the result of a migration.
+type MigrateReport struct {
+	Processes  []string      // IDs of the migrated processes
+	Files      []MigrateFile // the files that have been newly created on the target
+	Replaced   []MigrateFile // the files that already existed on the target and have been overwritten
+	RolledBack bool          // whether the migration failed and has been rolled back on the target
+}
+
+var diskfsRegex = regexp.MustCompile(`\{diskfs\}(/[^\s'"|,]+)`)
+
+// MigrateProcesses moves the selected processes with their metadata and optionally referenced files
+// from the source core to the target core. The processes are started on the target and only if all
+// of them are running, they will be stopped and deleted on the source. If any of the processes fails
+// to come up on the target, all processes and files that have been created on the target will be
+// removed again, files that have been overwritten on the target will be restored, and the source
+// remains untouched.
+func MigrateProcesses(ctx context.Context, source, target RestClient, opts MigrateOptions) (MigrateReport, error) {
+	report := MigrateReport{
+		Processes: []string{},
+		Files:     []MigrateFile{},
+		Replaced:  []MigrateFile{},
+	}
+
+	if !opts.All && isEmptySelection(opts.Select) {
+		return report, fmt.Errorf("empty selection, set All to migrate all processes")
+	}
+
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = time.Minute
+	}
+
+	archive, err := ExportProcesses(source, ExportOptions{Select: opts.Select})
+	if err != nil {
+		return report, err
+	}
+
+	existing, err := target.ProcessList(ProcessListOptions{Filter: []string{"state"}})
+	if err != nil {
+		return report, err
+	}
+
+	for _, e := range existing {
+		for _, p := range archive.Processes {
+			if p.Config.ID == e.ID {
+				return report, fmt.Errorf("process %s already exists on the target", e.ID)
+			}
+		}
+	}
+
+	files := append([]MigrateFile{}, opts.Files...)
+	if opts.CopyReferencedFiles {
+		files = append(files, referencedFiles(archive.Processes)...)
+	}
+
+	// backups are the original contents of the replaced files on the target
+	backups := [][]byte{}
+
+	rollback := func(cause error) (MigrateReport, error) {
+		for _, id := range report.Processes {
+			target.ProcessDelete(id)
+		}
+
+		for _, f := range report.Files {
+			target.FilesystemDeleteFile(f.Filesystem, f.Path)
+		}
+
+		for i, f := range report.Replaced {
+			target.FilesystemAddFile(f.Filesystem, f.Path, bytes.NewReader(backups[i]))
+		}
+
+		report.RolledBack = true
+
+		return report, cause
+	}
+
+	for _, f := range files {
+		if target.FilesystemHasFile(f.Filesystem, f.Path) {
+			backup, err := readFile(target, f)
+			if err != nil {
+				return rollback(fmt.Errorf("backing up %s:%s on the target: %w", f.Filesystem, f.Path, err))
+			}
+
+			backups = append(backups, backup)
+			report.Replaced = append(report.Replaced, f)
+		} else {
+			report.Files = append(report.Files, f)
+		}
+
+		if err := copyFile(source, target, f); err != nil {
+			return rollback(fmt.Errorf("copying %s:%s: %w", f.Filesystem, f.Path, err))
+		}
+	}
+
+	for _, p := range archive.Processes {
+		if err := target.ProcessAdd(p.Config); err != nil {
+			return rollback(fmt.Errorf("adding process %s: %w", p.Config.ID, err))
+		}
+
+		report.Processes = append(report.Processes, p.Config.ID)
+
+		for key, m := range p.Metadata {
+			if err := target.ProcessMetadataSet(p.Config.ID, key, m); err != nil {
+				return rollback(fmt.Errorf("setting metadata %s of process %s: %w", key, p.Config.ID, err))
+			}
+		}
+
+		if err := target.ProcessCommand(p.Config.ID, "start"); err != nil {
+			return rollback(fmt.Errorf("starting process %s: %w", p.Config.ID, err))
+		}
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, opts.StartTimeout)
+	defer cancel()
+
+	for _, id := range report.Processes {
+		if _, err := WaitForState(wctx, target, id, "running"); err != nil {
+			return rollback(fmt.Errorf("process %s didn't come up on the target: %w", id, err))
+		}
+	}
+
+	for _, id := range report.Processes {
+		if err := source.ProcessCommand(id, "stop"); err != nil {
+			return report, fmt.Errorf("stopping process %s on the source: %w", id, err)
+		}
+
+		if opts.KeepSource {
+			continue
+		}
+
+		if err := source.ProcessDelete(id); err != nil {
+			return report, fmt.Errorf("deleting process %s on the source: %w", id, err)
+		}
+	}
+
+	return report, nil
+}
+
+// referencedFiles returns the files on the disk filesystem that are referenced in the inputs of the processes.
+func referencedFiles(processes []ArchiveProcess) []MigrateFile {
+	files := []MigrateFile{}
+	seen := map[string]struct{}{}
+
+	add := func(s string) {
+		for _, m := range diskfsRegex.FindAllStringSubmatch(s, -1) {
+			if _, ok := seen[m[1]]; ok {
+				continue
+			}
+
+			seen[m[1]] = struct{}{}
+			files = append(files, MigrateFile{Filesystem: "disk", Path: m[1]})
+		}
+	}
+
+	for _, p := range processes {
+		for _, input := range p.Config.Input {
+			add(input.Address)
+
+			for _, o := range input.Options {
+				add(o)
+			}
+		}
+
+		for _, output := range p.Config.Output {
+			for _, o := range output.Options {
+				add(o)
+			}
+		}
+	}
+
+	return files
+}
+
+func readFile(client RestClient, f MigrateFile) ([]byte, error) {
+	data, err := client.FilesystemGetFile(f.Filesystem, f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer data.Close()
+
+	return io.ReadAll(data)
+}
+
+func copyFile(source, target RestClient, f MigrateFile) error {
+	data, err := source.FilesystemGetFile(f.Filesystem, f.Path)
+	if err != nil {
+		return err
+	}
+
+	defer data.Close()
+
+	return target.FilesystemAddFile(f.Filesystem, f.Path, data)
+}
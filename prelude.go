@@ -0,0 +1,300 @@
+package coreclient
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// PreludeStream is a stream of an input or output as described in the prelude of an ffmpeg process.
+type PreludeStream struct {
+	api.ProbeIO
+
+	Profile    string // e.g. "Main" or "LC"
+	SampleFmt  string // audio sample format, e.g. "fltp"
+	Resolution string // e.g. "1920x1080", empty for non-video streams
+}
+
+// PreludeMapping is a stream mapping from an input stream to an output stream.
+type PreludeMapping struct {
+	InputIndex   uint64
+	InputStream  uint64
+	OutputIndex  uint64
+	OutputStream uint64
+	Copy         bool   // whether the stream is copied without transcoding
+	Decoder      string // e.g. "h264 (native)", empty if copied
+	Encoder      string // e.g. "h264 (libx264)", empty if copied
+}
+
+// Prelude is the parsed prelude of an ffmpeg process.
+type Prelude struct {
+	Inputs  []PreludeStream
+	Outputs []PreludeStream
+	Mapping []PreludeMapping
+}
+
+// Transcodes returns the mappings that are not copied.
+func (p Prelude) Transcodes() []PreludeMapping {
+	m := []PreludeMapping{}
+
+	for _, x := range p.Mapping {
+		if !x.Copy {
+			m = append(m, x)
+		}
+	}
+
+	return m
+}
+
+var (
+	preludeIORegex       = regexp.MustCompile(`^(Input|Output) #(\d+), (.+), (?:from|to) '(.*)':$`)
+	preludeDurationRegex = regexp.MustCompile(`^Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+	preludeStreamRegex   = regexp.MustCompile(`^Stream #(\d+):(\d+)(?:\[0x[0-9a-fA-F]+\])?(?:\(([^)]+)\))?: (Video|Audio|Subtitle|Data|Attachment): (.*)$`)
+	preludeMappingRegex  = regexp.MustCompile(`^Stream #(\d+):(\d+) -> #(\d+):(\d+) \((.*)\)$`)
+	preludeCodecRegex    = regexp.MustCompile(`^([a-zA-Z0-9_\-]+)(?: \(([^)\[]+)\))?`)
+	preludeResRegex      = regexp.MustCompile(`^(\d+)x(\d+)`)
+	preludeFPSRegex      = regexp.MustCompile(`^(\d+(?:\.\d+)?k?) fps$`)
+	preludeBitrateRegex  = regexp.MustCompile(`^(\d+) kb/s`)
+	preludeHzRegex       = regexp.MustCompile(`^(\d+) Hz$`)
+	preludeComponent     = regexp.MustCompile(`^\[[^\]]+ @ 0x[0-9a-fA-F]+\]\s*`)
+)
+
+// ParsePrelude parses the prelude lines of a process report into the described inputs,
+// outputs and the stream mapping.
+func ParsePrelude(lines []string) Prelude {
+	p := Prelude{
+		Inputs:  []PreludeStream{},
+		Outputs: []PreludeStream{},
+		Mapping: []PreludeMapping{},
+	}
+
+	section := ""
+	address := ""
+	format := ""
+	duration := json.Number("")
+
+	for _, line := range lines {
+		line = preludeComponent.ReplaceAllString(line, "")
+		trimmed := strings.TrimSpace(line)
+
+		if m := preludeIORegex.FindStringSubmatch(trimmed); m != nil {
+			section = strings.ToLower(m[1])
+			format = m[3]
+			address = m[4]
+			duration = ""
+			continue
+		}
+
+		if trimmed == "Stream mapping:" {
+			section = "mapping"
+			continue
+		}
+
+		switch section {
+		case "mapping":
+			if m := preludeMappingRegex.FindStringSubmatch(trimmed); m != nil {
+				p.Mapping = append(p.Mapping, parsePreludeMapping(m))
+				continue
+			}
+
+			if !strings.HasPrefix(line, " ") {
+				section = ""
+			}
+		case "input", "output":
+			if m := preludeDurationRegex.FindStringSubmatch(trimmed); m != nil {
+				h, _ := strconv.ParseFloat(m[1], 64)
+				min, _ := strconv.ParseFloat(m[2], 64)
+				s, _ := strconv.ParseFloat(m[3], 64)
+				duration = json.Number(strconv.FormatFloat(h*3600+min*60+s, 'f', -1, 64))
+				continue
+			}
+
+			m := preludeStreamRegex.FindStringSubmatch(trimmed)
+			if m == nil {
+				continue
+			}
+
+			stream := parsePreludeStream(m)
+			stream.Address = address
+			stream.Format = format
+			stream.Duration = duration
+
+			if section == "input" {
+				p.Inputs = append(p.Inputs, stream)
+			} else {
+				p.Outputs = append(p.Outputs, stream)
+			}
+		}
+	}
+
+	for _, m := range p.Mapping {
+		coder := "copy"
+		if !m.Copy {
+			coder = m.Encoder
+		}
+
+		for i, s := range p.Outputs {
+			if s.Index == m.OutputIndex && s.Stream == m.OutputStream {
+				p.Outputs[i].Coder = coder
+			}
+		}
+
+		if m.Copy {
+			continue
+		}
+
+		for i, s := range p.Inputs {
+			if s.Index == m.InputIndex && s.Stream == m.InputStream {
+				p.Inputs[i].Coder = m.Decoder
+			}
+		}
+	}
+
+	return p
+}
+
+func parsePreludeMapping(m []string) PreludeMapping {
+	mapping := PreludeMapping{}
+
+	mapping.InputIndex, _ = strconv.ParseUint(m[1], 10, 64)
+	mapping.InputStream, _ = strconv.ParseUint(m[2], 10, 64)
+	mapping.OutputIndex, _ = strconv.ParseUint(m[3], 10, 64)
+	mapping.OutputStream, _ = strconv.ParseUint(m[4], 10, 64)
+
+	if m[5] == "copy" {
+		mapping.Copy = true
+		return mapping
+	}
+
+	if before, after, found := strings.Cut(m[5], " -> "); found {
+		mapping.Decoder = before
+		mapping.Encoder = after
+	}
+
+	return mapping
+}
+
+func parsePreludeStream(m []string) PreludeStream {
+	s := PreludeStream{}
+
+	s.Index, _ = strconv.ParseUint(m[1], 10, 64)
+	s.Stream, _ = strconv.ParseUint(m[2], 10, 64)
+	s.Language = m[3]
+	s.Type = strings.ToLower(m[4])
+
+	parts := splitPreludeDetails(m[5])
+	if len(parts) == 0 {
+		return s
+	}
+
+	if c := preludeCodecRegex.FindStringSubmatch(parts[0]); c != nil {
+		s.Codec = c[1]
+		s.Profile = c[2]
+	}
+
+	for i, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+
+		if b := preludeBitrateRegex.FindStringSubmatch(part); b != nil {
+			s.Bitrate = json.Number(b[1])
+			continue
+		}
+
+		switch s.Type {
+		case "video":
+			if i == 0 {
+				s.Pixfmt, _, _ = strings.Cut(part, "(")
+				continue
+			}
+
+			if r := preludeResRegex.FindStringSubmatch(part); r != nil {
+				s.Width, _ = strconv.ParseUint(r[1], 10, 64)
+				s.Height, _ = strconv.ParseUint(r[2], 10, 64)
+				s.Resolution = r[1] + "x" + r[2]
+				continue
+			}
+
+			if f := preludeFPSRegex.FindStringSubmatch(part); f != nil {
+				fps := f[1]
+				if strings.HasSuffix(fps, "k") {
+					v, _ := strconv.ParseFloat(strings.TrimSuffix(fps, "k"), 64)
+					fps = strconv.FormatFloat(v*1000, 'f', -1, 64)
+				}
+				s.FPS = json.Number(fps)
+			}
+		case "audio":
+			if h := preludeHzRegex.FindStringSubmatch(part); h != nil {
+				s.Sampling, _ = strconv.ParseUint(h[1], 10, 64)
+				continue
+			}
+
+			if i == 1 {
+				s.Layout, _, _ = strings.Cut(part, "(")
+				s.Channels = channelsOfLayout(s.Layout)
+				continue
+			}
+
+			if i == 2 {
+				s.SampleFmt = part
+			}
+		}
+	}
+
+	return s
+}
+
+// splitPreludeDetails splits the details of a stream at commas that are not within brackets.
+func splitPreludeDetails(s string) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+
+	for i, c := range s {
+		switch c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	parts = append(parts, strings.TrimSpace(s[start:]))
+
+	return parts
+}
+
+func channelsOfLayout(layout string) uint64 {
+	switch layout {
+	case "mono":
+		return 1
+	case "stereo":
+		return 2
+	case "2.1", "3.0":
+		return 3
+	case "4.0", "quad":
+		return 4
+	case "5.0":
+		return 5
+	case "5.1", "6.0":
+		return 6
+	case "7.1":
+		return 8
+	}
+
+	if n, _, found := strings.Cut(layout, " channels"); found {
+		c, _ := strconv.ParseUint(n, 10, 64)
+		return c
+	}
+
+	return 0
+}
@@ -0,0 +1,186 @@
+package coreclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+func TestParsePrelude(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		inputs  []PreludeStream
+		outputs []PreludeStream
+		mapping []PreludeMapping
+	}{
+		{
+			name: "transcode",
+			lines: []string{
+				"Input #0, flv, from 'rtmp://localhost/live/in':",
+				"  Metadata:",
+				"    encoder         : obs-output module (libobs version 29.1.3)",
+				"  Duration: 00:00:00.00, start: 0.000000, bitrate: N/A",
+				"  Stream #0:0: Audio: aac (LC), 48000 Hz, stereo, fltp, 160 kb/s",
+				"  Stream #0:1: Video: h264 (High), yuv420p(tv, bt709, progressive), 1920x1080 [SAR 1:1 DAR 16:9], 6000 kb/s, 30 fps, 30 tbr, 1k tbn",
+				"Input #1, mpegts, from 'srt://camera:6000':",
+				"  Duration: N/A, start: 1.400000, bitrate: N/A",
+				"  Program 1 ",
+				"  Stream #1:0[0x100](und): Video: hevc (Main) ([36][0][0][0] / 0x0024), yuv420p(tv), 3840x2160 [SAR 1:1 DAR 16:9], 59.94 fps, 59.94 tbr, 90k tbn",
+				"  Stream #1:1[0x101](eng): Audio: mp2 ([3][0][0][0] / 0x0003), 48000 Hz, stereo, s16p, 192 kb/s",
+				"Stream mapping:",
+				"  Stream #0:1 -> #0:0 (h264 (native) -> h264 (libx264))",
+				"  Stream #0:0 -> #0:1 (copy)",
+				"Press [q] to stop, [?] for help",
+				"[libx264 @ 0x5581c3a3c2c0] using SAR=1/1",
+				"Output #0, hls, to '/core/data/memfs/stream.m3u8':",
+				"  Metadata:",
+				"    encoder         : Lavf60.3.100",
+				"  Stream #0:0: Video: h264, yuv420p(progressive), 1280x720 [SAR 1:1 DAR 16:9], q=2-31, 2500 kb/s, 30 fps, 90k tbn",
+				"    Metadata:",
+				"      encoder         : Lavc60.3.100 libx264",
+				"  Stream #0:1: Audio: aac (LC), 48000 Hz, stereo, fltp, 160 kb/s",
+			},
+			inputs: []PreludeStream{
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://localhost/live/in", Format: "flv", Index: 0, Stream: 0, Type: "audio", Codec: "aac",
+						Bitrate: "160", Duration: "0", Sampling: 48000, Layout: "stereo", Channels: 2,
+					},
+					Profile:   "LC",
+					SampleFmt: "fltp",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://localhost/live/in", Format: "flv", Index: 0, Stream: 1, Type: "video", Codec: "h264",
+						Coder: "h264 (native)", Bitrate: "6000", Duration: "0", FPS: "30", Pixfmt: "yuv420p", Width: 1920, Height: 1080,
+					},
+					Profile:    "High",
+					Resolution: "1920x1080",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "srt://camera:6000", Format: "mpegts", Index: 1, Stream: 0, Language: "und", Type: "video", Codec: "hevc",
+						FPS: "59.94", Pixfmt: "yuv420p", Width: 3840, Height: 2160,
+					},
+					Profile:    "Main",
+					Resolution: "3840x2160",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "srt://camera:6000", Format: "mpegts", Index: 1, Stream: 1, Language: "eng", Type: "audio", Codec: "mp2",
+						Bitrate: "192", Sampling: 48000, Layout: "stereo", Channels: 2,
+					},
+					SampleFmt: "s16p",
+				},
+			},
+			outputs: []PreludeStream{
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "/core/data/memfs/stream.m3u8", Format: "hls", Index: 0, Stream: 0, Type: "video", Codec: "h264",
+						Coder: "h264 (libx264)", Bitrate: "2500", FPS: "30", Pixfmt: "yuv420p", Width: 1280, Height: 720,
+					},
+					Resolution: "1280x720",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "/core/data/memfs/stream.m3u8", Format: "hls", Index: 0, Stream: 1, Type: "audio", Codec: "aac",
+						Coder: "copy", Bitrate: "160", Sampling: 48000, Layout: "stereo", Channels: 2,
+					},
+					Profile:   "LC",
+					SampleFmt: "fltp",
+				},
+			},
+			mapping: []PreludeMapping{
+				{InputIndex: 0, InputStream: 1, OutputIndex: 0, OutputStream: 0, Decoder: "h264 (native)", Encoder: "h264 (libx264)"},
+				{InputIndex: 0, InputStream: 0, OutputIndex: 0, OutputStream: 1, Copy: true},
+			},
+		},
+		{
+			name: "copy",
+			lines: []string{
+				"[flv @ 0x55d0c8a4e040] Packet mismatch 121 11 11",
+				"Input #0, flv, from 'rtmp://localhost/live/in':",
+				"  Duration: 00:01:02.50, start: 0.000000, bitrate: N/A",
+				"  Stream #0:0: Video: h264 (Main), yuv420p(progressive), 1280x720, 1k fps, 29.97 tbr, 1k tbn",
+				"  Stream #0:1: Audio: aac (LC), 44100 Hz, mono, fltp",
+				"Stream mapping:",
+				"  Stream #0:0 -> #0:0 (copy)",
+				"  Stream #0:1 -> #0:1 (copy)",
+				"Output #0, flv, to 'rtmp://a.rtmp.youtube.com/live2/key':",
+				"  Stream #0:0: Video: h264 (Main) ([7][0][0][0] / 0x0007), yuv420p(progressive), 1280x720, q=2-31, 1k fps, 29.97 tbr, 1k tbn",
+				"  Stream #0:1: Audio: aac (LC) ([10][0][0][0] / 0x000A), 44100 Hz, mono, fltp",
+			},
+			inputs: []PreludeStream{
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://localhost/live/in", Format: "flv", Index: 0, Stream: 0, Type: "video", Codec: "h264",
+						Duration: "62.5", FPS: "1000", Pixfmt: "yuv420p", Width: 1280, Height: 720,
+					},
+					Profile:    "Main",
+					Resolution: "1280x720",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://localhost/live/in", Format: "flv", Index: 0, Stream: 1, Type: "audio", Codec: "aac",
+						Duration: "62.5", Sampling: 44100, Layout: "mono", Channels: 1,
+					},
+					Profile:   "LC",
+					SampleFmt: "fltp",
+				},
+			},
+			outputs: []PreludeStream{
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://a.rtmp.youtube.com/live2/key", Format: "flv", Index: 0, Stream: 0, Type: "video", Codec: "h264",
+						Coder: "copy", FPS: "1000", Pixfmt: "yuv420p", Width: 1280, Height: 720,
+					},
+					Profile:    "Main",
+					Resolution: "1280x720",
+				},
+				{
+					ProbeIO: api.ProbeIO{
+						Address: "rtmp://a.rtmp.youtube.com/live2/key", Format: "flv", Index: 0, Stream: 1, Type: "audio", Codec: "aac",
+						Coder: "copy", Sampling: 44100, Layout: "mono", Channels: 1,
+					},
+					Profile:   "LC",
+					SampleFmt: "fltp",
+				},
+			},
+			mapping: []PreludeMapping{
+				{InputIndex: 0, InputStream: 0, OutputIndex: 0, OutputStream: 0, Copy: true},
+				{InputIndex: 0, InputStream: 1, OutputIndex: 0, OutputStream: 1, Copy: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		p := ParsePrelude(test.lines)
+
+		if !reflect.DeepEqual(p.Inputs, test.inputs) {
+			t.Errorf("%s: inputs:\n got %+v\nwant %+v", test.name, p.Inputs, test.inputs)
+		}
+
+		if !reflect.DeepEqual(p.Outputs, test.outputs) {
+			t.Errorf("%s: outputs:\n got %+v\nwant %+v", test.name, p.Outputs, test.outputs)
+		}
+
+		if !reflect.DeepEqual(p.Mapping, test.mapping) {
+			t.Errorf("%s: mapping:\n got %+v\nwant %+v", test.name, p.Mapping, test.mapping)
+		}
+	}
+}
+
+func TestPreludeTranscodes(t *testing.T) {
+	p := ParsePrelude([]string{
+		"Stream mapping:",
+		"  Stream #0:0 -> #0:0 (h264 (native) -> h264 (libx264))",
+		"  Stream #0:1 -> #0:1 (copy)",
+	})
+
+	transcodes := p.Transcodes()
+	if len(transcodes) != 1 || transcodes[0].InputStream != 0 || transcodes[0].Encoder != "h264 (libx264)" {
+		t.Errorf("unexpected transcodes: %+v", transcodes)
+	}
+}
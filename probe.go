@@ -0,0 +1,102 @@
+package coreclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ProbeOptions are the options for ProbeInput.
+type ProbeOptions struct {
+	// Options are the ffmpeg options for the input, e.g. ["-rtsp_transport", "tcp"].
+	Options []string
+
+	// Process are global ffmpeg options for the probe process. Optional.
+	Process []string
+}
+
+// ProbeInput probes an arbitrary input address without a permanent process. If the core supports it,
+// the config is probed directly. Otherwise a temporary process with a unique ID is created, probed and
+// deleted again, even if the probe fails or the context is done before the probe finished.
+func ProbeInput(ctx context.Context, client RestClient, address string, opts ProbeOptions) (api.Probe, error) {
+	config := api.ProcessConfig{
+		ID:      "probe-" + randomID(),
+		Type:    "ffmpeg",
+		Options: append([]string{}, opts.Process...),
+		Input: []api.ProcessConfigIO{
+			{
+				ID:      "input_0",
+				Address: address,
+				Options: append([]string{}, opts.Options...),
+			},
+		},
+		Output: []api.ProcessConfigIO{
+			{
+				ID:      "output_0",
+				Address: "-",
+				Options: []string{"-f", "null"},
+			},
+		},
+		Autostart: false,
+		Reconnect: false,
+	}
+
+	type result struct {
+		probe api.Probe
+		err   error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		probe, err := client.ProcessProbeConfig(config)
+		if err == nil || !isUnsupported(err) {
+			done <- result{probe, err}
+			return
+		}
+
+		if err := client.ProcessAdd(config); err != nil {
+			done <- result{api.Probe{}, err}
+			return
+		}
+
+		defer client.ProcessDelete(config.ID)
+
+		probe, err = client.ProcessProbe(config.ID)
+		done <- result{probe, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return api.Probe{}, ctx.Err()
+	case r := <-done:
+		return r.probe, r.err
+	}
+}
+
+// isUnsupported returns whether the error indicates that the core doesn't support the called method,
+// either because of the version check of the client or because the endpoint doesn't exist.
+func isUnsupported(err error) bool {
+	if errors.As(err, &VersionError{}) {
+		return true
+	}
+
+	var e api.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.Code == http.StatusNotFound || e.Code == http.StatusMethodNotAllowed
+}
+
+// randomID returns a random hex string.
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
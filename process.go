@@ -120,6 +120,24 @@ func (r *restclient) ProcessProbe(id string) (api.Probe, error) {
 	return p, err
 }
 
+func (r *restclient) ProcessProbeConfig(p api.ProcessConfig) (api.Probe, error) {
+	var probe api.Probe
+
+	var buf bytes.Buffer
+
+	e := json.NewEncoder(&buf)
+	e.Encode(p)
+
+	data, err := r.call("POST", "/v3/process/probe", "application/json", &buf)
+	if err != nil {
+		return probe, err
+	}
+
+	err = json.Unmarshal(data, &probe)
+
+	return probe, err
+}
+
 func (r *restclient) ProcessConfig(id string) (api.ProcessConfig, error) {
 	var p api.ProcessConfig
 
@@ -0,0 +1,806 @@
+package coreclient
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// A process query is an expression that is evaluated on an api.Process, e.g.
+//
+//	state.exec == "failed" && state.cpu_usage > 80 && reference ~ "event-*" && metadata.owner == "sports"
+//
+// Fields are addressed by the JSON names of the fields of api.Process, separated by dots. Fields
+// within lists (e.g. config.input.address) match if any of the elements matches. Fields below
+// metadata are not type-checked. The operators are ==, !=, <, <=, >, >=, ~ (glob match), !~
+// (glob mismatch), && (and), || (or) and ! (not). Literals are strings in single or double quotes,
+// numbers, true and false. In glob patterns "*" matches any sequence of characters and "?"
+// matches any single character.
+
+// queryType is the type of a field or a literal in a query.
+type queryType int
+
+const (
+	queryTypeString queryType = iota
+	queryTypeNumber
+	queryTypeBool
+	queryTypeDynamic
+)
+
+func (t queryType) String() string {
+	switch t {
+	case queryTypeString:
+		return "string"
+	case queryTypeNumber:
+		return "number"
+	case queryTypeBool:
+		return "bool"
+	}
+
+	return "dynamic"
+}
+
+// queryField is a type-checked path to a field of api.Process.
+type queryField struct {
+	path []string
+	typ  queryType
+}
+
+var processType = reflect.TypeOf(api.Process{})
+
+// parseQueryField resolves the path against api.Process.
+func parseQueryField(s string) (queryField, error) {
+	f := queryField{
+		path: strings.Split(s, "."),
+	}
+
+	t := processType
+
+	for i, name := range f.path {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+
+		if t.Kind() == reflect.Interface {
+			f.typ = queryTypeDynamic
+			return f, nil
+		}
+
+		if t.Kind() != reflect.Struct {
+			return f, fmt.Errorf("unknown field %s: %s is not an object", s, strings.Join(f.path[:i], "."))
+		}
+
+		field, ok := jsonField(t, name)
+		if !ok {
+			return f, fmt.Errorf("unknown field %s", s)
+		}
+
+		t = field.Type
+	}
+
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		f.typ = queryTypeString
+	case reflect.Bool:
+		f.typ = queryTypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		f.typ = queryTypeNumber
+	case reflect.Interface:
+		f.typ = queryTypeDynamic
+	default:
+		return f, fmt.Errorf("field %s is not a value", s)
+	}
+
+	return f, nil
+}
+
+// jsonField returns the field of the struct with the given JSON name. Fields of embedded structs
+// are considered as well.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if f, ok := jsonField(field.Type, name); ok {
+				f.Index = append([]int{i}, f.Index...)
+				return f, true
+			}
+
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "-" || len(field.PkgPath) != 0 {
+			continue
+		}
+
+		if tag == name || (len(tag) == 0 && field.Name == name) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// values returns all values of the field in the process.
+func (f queryField) values(p api.Process) []interface{} {
+	values := []interface{}{}
+
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i), path)
+			}
+			return
+		}
+
+		if len(path) == 0 {
+			values = append(values, v.Interface())
+			return
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := jsonField(v.Type(), path[0])
+			if !ok {
+				return
+			}
+			walk(v.FieldByIndex(field.Index), path[1:])
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return
+			}
+			e := v.MapIndex(reflect.ValueOf(path[0]).Convert(v.Type().Key()))
+			if !e.IsValid() {
+				return
+			}
+			walk(e, path[1:])
+		}
+	}
+
+	walk(reflect.ValueOf(p), f.path)
+
+	return values
+}
+
+// queryNode is a node of the syntax tree of a query.
+type queryNode interface {
+	eval(p api.Process) bool
+}
+
+type queryAnd struct{ left, right queryNode }
+
+func (n queryAnd) eval(p api.Process) bool { return n.left.eval(p) && n.right.eval(p) }
+
+type queryOr struct{ left, right queryNode }
+
+func (n queryOr) eval(p api.Process) bool { return n.left.eval(p) || n.right.eval(p) }
+
+type queryNot struct{ node queryNode }
+
+func (n queryNot) eval(p api.Process) bool { return !n.node.eval(p) }
+
+type queryCompare struct {
+	field queryField
+	op    string
+	value interface{} // string, float64, bool, or *regexp.Regexp for glob matches
+}
+
+func (n queryCompare) eval(p api.Process) bool {
+	values := n.field.values(p)
+
+	// Negated operators are true if none of the values matches
+	switch n.op {
+	case "!=":
+		return !queryCompare{n.field, "==", n.value}.eval(p)
+	case "!~":
+		return !queryCompare{n.field, "~", n.value}.eval(p)
+	}
+
+	for _, v := range values {
+		if compareQueryValue(v, n.op, n.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compareQueryValue compares a value of a field with a literal.
+func compareQueryValue(v interface{}, op string, literal interface{}) bool {
+	switch l := literal.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case "==":
+			return s == l
+		case "<":
+			return s < l
+		case "<=":
+			return s <= l
+		case ">":
+			return s > l
+		case ">=":
+			return s >= l
+		}
+	case *regexp.Regexp:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		return op == "~" && l.MatchString(s)
+	case float64:
+		f, ok := toFloat(v)
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case "==":
+			return f == l
+		case "<":
+			return f < l
+		case "<=":
+			return f <= l
+		case ">":
+			return f > l
+		case ">=":
+			return f >= l
+		}
+	case bool:
+		b, ok := v.(bool)
+		if !ok {
+			return false
+		}
+
+		if op == "==" {
+			return b == l
+		}
+	}
+
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		// Only named string types, e.g. json.Number, are considered numbers
+		if rv.Type() == reflect.TypeOf("") {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// queryToken is a token of a query.
+type queryToken struct {
+	kind  string // "ident", "string", "number", "op", "(", ")", "eof"
+	value string
+	pos   int
+}
+
+func lexQuery(s string) ([]queryToken, error) {
+	tokens := []queryToken{}
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, queryToken{kind: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			i++
+
+			var b strings.Builder
+			for ; i < len(runes) && runes[i] != c; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+
+			i++
+			tokens = append(tokens, queryToken{kind: "string", value: b.String(), pos: start})
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: "number", value: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.' || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: "ident", value: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "<", ">", "~", "!"} {
+				if strings.HasPrefix(string(runes[i:]), o) {
+					op = o
+					break
+				}
+			}
+
+			if len(op) == 0 {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+
+			i += len(op)
+			tokens = append(tokens, queryToken{kind: "op", value: op, pos: start})
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: "eof", pos: len(runes)})
+
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == "op" && p.peek().value == "||" {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = queryOr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == "op" && p.peek().value == "&&" {
+		p.next()
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = queryAnd{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	t := p.peek()
+
+	if t.kind == "op" && t.value == "!" {
+		p.next()
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return queryNot{node}, nil
+	}
+
+	if t.kind == "(" {
+		p.next()
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.kind != ")" {
+			return nil, fmt.Errorf("expected ) at position %d", t.pos)
+		}
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	t := p.next()
+	if t.kind != "ident" {
+		return nil, fmt.Errorf("expected field at position %d", t.pos)
+	}
+
+	field, err := parseQueryField(t.value)
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if op.kind != "op" || op.value == "&&" || op.value == "||" || op.value == "!" {
+		// A bare boolean field
+		if field.typ == queryTypeBool || field.typ == queryTypeDynamic {
+			return queryCompare{field: field, op: "==", value: true}, nil
+		}
+
+		return nil, fmt.Errorf("expected operator at position %d", op.pos)
+	}
+
+	p.next()
+
+	lit := p.next()
+
+	var value interface{}
+	var typ queryType
+
+	switch {
+	case lit.kind == "string":
+		value, typ = lit.value, queryTypeString
+	case lit.kind == "number":
+		f, err := strconv.ParseFloat(lit.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at position %d: %w", lit.pos, err)
+		}
+		value, typ = f, queryTypeNumber
+	case lit.kind == "ident" && (lit.value == "true" || lit.value == "false"):
+		value, typ = lit.value == "true", queryTypeBool
+	default:
+		return nil, fmt.Errorf("expected literal at position %d", lit.pos)
+	}
+
+	if field.typ != queryTypeDynamic && field.typ != typ {
+		return nil, fmt.Errorf("can't compare %s field %s with %s at position %d", field.typ, t.value, typ, lit.pos)
+	}
+
+	switch op.value {
+	case "~", "!~":
+		if typ != queryTypeString {
+			return nil, fmt.Errorf("operator %s requires a string at position %d", op.value, lit.pos)
+		}
+	case "<", "<=", ">", ">=":
+		if typ == queryTypeBool {
+			return nil, fmt.Errorf("operator %s can't be used with bool at position %d", op.value, op.pos)
+		}
+	}
+
+	if op.value == "~" || op.value == "!~" {
+		value = compileGlob(lit.value)
+	}
+
+	return queryCompare{field: field, op: op.value, value: value}, nil
+}
+
+// compileGlob compiles a glob pattern into a regular expression. As with the ID and reference
+// patterns of the core, "*" matches any sequence of characters, including "/", and "?" matches
+// any single character. All other characters match themselves.
+func compileGlob(pattern string) *regexp.Regexp {
+	var b strings.Builder
+
+	b.WriteString("^")
+
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// ProcessFilter is a parsed and type-checked process query.
+type ProcessFilter struct {
+	expr string
+	root queryNode
+}
+
+// ParseProcessFilter parses a process query expression. An empty expression matches all processes.
+func ParseProcessFilter(expr string) (*ProcessFilter, error) {
+	f := &ProcessFilter{
+		expr: expr,
+	}
+
+	if len(strings.TrimSpace(expr)) == 0 {
+		return f, nil
+	}
+
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	f.root, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %s at position %d", t.value, t.pos)
+	}
+
+	return f, nil
+}
+
+func (f *ProcessFilter) String() string {
+	return f.expr
+}
+
+// Match returns whether the process matches the query.
+func (f *ProcessFilter) Match(p api.Process) bool {
+	if f.root == nil {
+		return true
+	}
+
+	return f.root.eval(p)
+}
+
+// Filter returns the processes that match the query.
+func (f *ProcessFilter) Filter(processes []api.Process) []api.Process {
+	matches := []api.Process{}
+
+	for _, p := range processes {
+		if f.Match(p) {
+			matches = append(matches, p)
+		}
+	}
+
+	return matches
+}
+
+// ProcessQuery is a query on a list of processes.
+type ProcessQuery struct {
+	// Where is the process query expression, e.g. `state.exec == "failed"`.
+	Where string
+
+	// OrderBy is a comma separated list of fields, each optionally followed by "asc" or "desc",
+	// e.g. "state.cpu_usage desc, id".
+	OrderBy string
+
+	// Select is the list of fields for the projection.
+	Select []string
+}
+
+// QueryProcesses filters and sorts the processes according to the query. The Select
+// field of the query is ignored, use ProjectProcesses for the projection.
+func QueryProcesses(processes []api.Process, query ProcessQuery) ([]api.Process, error) {
+	filter, err := ParseProcessFilter(query.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	result := filter.Filter(processes)
+
+	if err := SortProcesses(result, query.OrderBy); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SortProcesses sorts the processes in place. The order is a comma separated list of fields, each
+// optionally followed by "asc" or "desc". Processes with a missing field are sorted last.
+func SortProcesses(processes []api.Process, order string) error {
+	type term struct {
+		field queryField
+		desc  bool
+	}
+
+	terms := []term{}
+
+	for _, t := range strings.Split(order, ",") {
+		parts := strings.Fields(t)
+		if len(parts) == 0 {
+			continue
+		}
+
+		if len(parts) > 2 || (len(parts) == 2 && parts[1] != "asc" && parts[1] != "desc") {
+			return fmt.Errorf("invalid order %q", strings.TrimSpace(t))
+		}
+
+		field, err := parseQueryField(parts[0])
+		if err != nil {
+			return err
+		}
+
+		terms = append(terms, term{
+			field: field,
+			desc:  len(parts) == 2 && parts[1] == "desc",
+		})
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		for _, t := range terms {
+			a, b := t.field.values(processes[i]), t.field.values(processes[j])
+
+			if len(a) == 0 || len(b) == 0 {
+				if len(a) == len(b) {
+					continue
+				}
+				return len(b) == 0
+			}
+
+			c := compareValues(a[0], b[0])
+			if c == 0 {
+				continue
+			}
+
+			if t.desc {
+				return c > 0
+			}
+
+			return c < 0
+		}
+
+		return false
+	})
+
+	return nil
+}
+
+func compareValues(a, b interface{}) int {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+
+	return strings.Compare(sa, sb)
+}
+
+// ProjectProcesses returns for every process a map with the selected fields. A field within
+// a list is a list of all values. Missing fields are nil.
+func ProjectProcesses(processes []api.Process, fields []string) ([]map[string]interface{}, error) {
+	parsed := make([]queryField, 0, len(fields))
+
+	for _, f := range fields {
+		field, err := parseQueryField(f)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, field)
+	}
+
+	result := make([]map[string]interface{}, 0, len(processes))
+
+	for _, p := range processes {
+		row := map[string]interface{}{}
+
+		for i, f := range parsed {
+			values := f.values(p)
+
+			switch {
+			case len(values) == 0:
+				row[fields[i]] = nil
+			case len(values) == 1 && !f.inList():
+				row[fields[i]] = values[0]
+			default:
+				row[fields[i]] = values
+			}
+		}
+
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// inList returns whether the path of the field traverses a list.
+func (f queryField) inList() bool {
+	t := processType
+
+	for _, name := range f.path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			return true
+		}
+
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+
+		field, ok := jsonField(t, name)
+		if !ok {
+			return false
+		}
+
+		t = field.Type
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
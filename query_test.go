@@ -0,0 +1,245 @@
+package coreclient
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+func queryTestProcesses() []api.Process {
+	return []api.Process{
+		{
+			ID:        "a",
+			Reference: "event-1",
+			Config: &api.ProcessConfig{
+				Input: []api.ProcessConfigIO{
+					{ID: "in", Address: "rtmp://host/live/key"},
+					{ID: "logo", Address: "{diskfs}/logo.png"},
+				},
+				Reconnect: true,
+			},
+			State:    &api.ProcessState{State: "failed", CPU: 90},
+			Metadata: map[string]interface{}{"owner": "sports", "priority": float64(2)},
+		},
+		{
+			ID:        "b",
+			Reference: "event-2",
+			Config: &api.ProcessConfig{
+				Input: []api.ProcessConfigIO{
+					{ID: "in", Address: "srt://host:6000"},
+				},
+			},
+			State:    &api.ProcessState{State: "running", CPU: 95},
+			Metadata: map[string]interface{}{"owner": "news", "nested": map[string]interface{}{"team": "x"}},
+		},
+		{
+			ID:        "c",
+			Reference: "other",
+			State:     &api.ProcessState{State: "failed", CPU: 10},
+		},
+	}
+}
+
+func queryIDs(t *testing.T, expr string) []string {
+	t.Helper()
+
+	f, err := ParseProcessFilter(expr)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %s", expr, err)
+	}
+
+	ids := []string{}
+	for _, p := range f.Filter(queryTestProcesses()) {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
+
+func TestQueryFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		ids  []string
+	}{
+		{``, []string{"a", "b", "c"}},
+		{`id == "a"`, []string{"a"}},
+		{`id != "a"`, []string{"b", "c"}},
+		{`state.exec == "failed" && state.cpu_usage > 80`, []string{"a"}},
+		{`state.cpu_usage >= 90`, []string{"a", "b"}},
+		{`state.cpu_usage < 90`, []string{"c"}},
+		{`config.reconnect`, []string{"a"}},
+		{`config.reconnect == false`, []string{"b"}},
+		{`state.exec == 'failed' && !config.reconnect`, []string{"c"}},
+	}
+
+	for _, tc := range tests {
+		ids := queryIDs(t, tc.expr)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.expr, ids, tc.ids)
+		}
+	}
+}
+
+func TestQueryPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		ids  []string
+	}{
+		// && binds stronger than ||
+		{`id == "c" || id == "a" && state.exec == "running"`, []string{"c"}},
+		{`(id == "c" || id == "a") && state.exec == "failed"`, []string{"a", "c"}},
+		// ! binds stronger than &&
+		{`!id == "a" && state.exec == "failed"`, []string{"c"}},
+		{`!(id == "a" || id == "b")`, []string{"c"}},
+	}
+
+	for _, tc := range tests {
+		ids := queryIDs(t, tc.expr)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.expr, ids, tc.ids)
+		}
+	}
+}
+
+func TestQueryGlob(t *testing.T) {
+	tests := []struct {
+		expr string
+		ids  []string
+	}{
+		{`reference ~ "event-*"`, []string{"a", "b"}},
+		{`reference !~ "event-*"`, []string{"c"}},
+		{`reference ~ "event-?"`, []string{"a", "b"}},
+		{`reference ~ "event"`, []string{}},
+		// "*" matches "/"
+		{`config.input.address ~ "rtmp://*"`, []string{"a"}},
+		{`config.input.address ~ "*/live/*"`, []string{"a"}},
+		// Other characters are matched literally
+		{`config.input.address ~ "{diskfs}/logo.png"`, []string{"a"}},
+		{`config.input.address ~ "{diskfs}/logo_png"`, []string{}},
+	}
+
+	for _, tc := range tests {
+		ids := queryIDs(t, tc.expr)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.expr, ids, tc.ids)
+		}
+	}
+}
+
+func TestQueryListFields(t *testing.T) {
+	tests := []struct {
+		expr string
+		ids  []string
+	}{
+		// Any element of the list matches
+		{`config.input.id == "logo"`, []string{"a"}},
+		{`config.input.id == "in"`, []string{"a", "b"}},
+		// Negations match if no element matches
+		{`config.input.id != "logo"`, []string{"b", "c"}},
+		{`config.input.address !~ "srt://*"`, []string{"a", "c"}},
+	}
+
+	for _, tc := range tests {
+		ids := queryIDs(t, tc.expr)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.expr, ids, tc.ids)
+		}
+	}
+}
+
+func TestQueryMetadata(t *testing.T) {
+	tests := []struct {
+		expr string
+		ids  []string
+	}{
+		{`metadata.owner == "sports"`, []string{"a"}},
+		{`metadata.owner ~ "*s"`, []string{"a", "b"}},
+		{`metadata.priority > 1`, []string{"a"}},
+		{`metadata.priority == "2"`, []string{}},
+		{`metadata.nested.team == "x"`, []string{"b"}},
+		{`metadata.missing == "x"`, []string{}},
+		{`metadata.missing != "x"`, []string{"a", "b", "c"}},
+	}
+
+	for _, tc := range tests {
+		ids := queryIDs(t, tc.expr)
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: got %v, want %v", tc.expr, ids, tc.ids)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []string{
+		`foo == 1`,
+		`state.exec == 1`,
+		`state.cpu_usage == "high"`,
+		`config.reconnect == "yes"`,
+		`state.cpu_usage ~ "9*"`,
+		`config.reconnect > true`,
+		`state.cpu_usage`,
+		`state == "running"`,
+		`id == "a" &&`,
+		`(id == "a"`,
+		`id == "a")`,
+		`id == "a`,
+		`id == $`,
+		`id "a"`,
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseProcessFilter(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestSortProcesses(t *testing.T) {
+	processes := queryTestProcesses()
+
+	if err := SortProcesses(processes, "state.cpu_usage desc"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ids := []string{processes[0].ID, processes[1].ID, processes[2].ID}
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+
+	if err := SortProcesses(processes, "state.exec, id desc"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ids = []string{processes[0].ID, processes[1].ID, processes[2].ID}
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+
+	for _, order := range []string{"foo", "id up", "id asc desc"} {
+		if err := SortProcesses(processes, order); err == nil {
+			t.Errorf("%s: expected an error", order)
+		}
+	}
+}
+
+func TestProjectProcesses(t *testing.T) {
+	rows, err := ProjectProcesses(queryTestProcesses(), []string{"id", "config.input.address", "metadata.owner"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []map[string]interface{}{
+		{"id": "a", "config.input.address": []interface{}{"rtmp://host/live/key", "{diskfs}/logo.png"}, "metadata.owner": "sports"},
+		{"id": "b", "config.input.address": []interface{}{"srt://host:6000"}, "metadata.owner": "news"},
+		{"id": "c", "config.input.address": nil, "metadata.owner": nil},
+	}
+
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+
+	if _, err := ProjectProcesses(queryTestProcesses(), []string{"foo"}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
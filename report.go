@@ -0,0 +1,191 @@
+package coreclient
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ProcessLogEntry is a parsed line of an ffmpeg process log.
+type ProcessLogEntry struct {
+	Time      time.Time
+	Level     string // e.g. "error", "warning", "info". Empty if not known.
+	Component string // e.g. "tls", "hls", "rtmp". Empty if the line has no component.
+	Message   string
+	Line      string // the raw line
+	Class     ProcessErrorClass
+}
+
+// ProcessErrorClass is the class of an error found in the log of a process.
+type ProcessErrorClass string
+
+const (
+	PROCESS_ERROR_NONE               ProcessErrorClass = ""
+	PROCESS_ERROR_CONNECTION_REFUSED ProcessErrorClass = "connection_refused"
+	PROCESS_ERROR_INPUT_NOT_FOUND    ProcessErrorClass = "input_not_found"
+	PROCESS_ERROR_CODEC_NOT_FOUND    ProcessErrorClass = "codec_not_found"
+	PROCESS_ERROR_DISK_FULL          ProcessErrorClass = "disk_full"
+	PROCESS_ERROR_PERMISSION_DENIED  ProcessErrorClass = "permission_denied"
+	PROCESS_ERROR_TIMEOUT            ProcessErrorClass = "timeout"
+	PROCESS_ERROR_OTHER              ProcessErrorClass = "other"
+)
+
+var errorClasses = []struct {
+	class   ProcessErrorClass
+	pattern *regexp.Regexp
+}{
+	{PROCESS_ERROR_CONNECTION_REFUSED, regexp.MustCompile(`(?i)connection refused|connection reset by peer`)},
+	{PROCESS_ERROR_INPUT_NOT_FOUND, regexp.MustCompile(`(?i)404 not found|no such file or directory|server returned 404`)},
+	{PROCESS_ERROR_CODEC_NOT_FOUND, regexp.MustCompile(`(?i)unknown (en|de)coder|(en|de)coder .* not found|codec not found`)},
+	{PROCESS_ERROR_DISK_FULL, regexp.MustCompile(`(?i)no space left on device`)},
+	{PROCESS_ERROR_PERMISSION_DENIED, regexp.MustCompile(`(?i)permission denied|403 forbidden|401 unauthorized`)},
+	{PROCESS_ERROR_TIMEOUT, regexp.MustCompile(`(?i)connection timed out|operation timed out`)},
+}
+
+// ffmpeg log lines have the form "[component @ 0x...] [level] message", where both the
+// component and the level are optional.
+var (
+	logComponentRegex = regexp.MustCompile(`^\[([a-zA-Z0-9_\-:]+) @ 0x[0-9a-fA-F]+\]\s*`)
+	logLevelRegex     = regexp.MustCompile(`^\[(panic|fatal|error|warning|info|verbose|debug|trace)\]\s*`)
+	logFailureRegex   = regexp.MustCompile(`(?i)error|failed|invalid|unable|could not|cannot`)
+)
+
+// ClassifyProcessError returns the error class of a log message. It returns PROCESS_ERROR_NONE
+// if the message doesn't look like an error.
+func ClassifyProcessError(message string) ProcessErrorClass {
+	for _, c := range errorClasses {
+		if c.pattern.MatchString(message) {
+			return c.class
+		}
+	}
+
+	if logFailureRegex.MatchString(message) {
+		return PROCESS_ERROR_OTHER
+	}
+
+	return PROCESS_ERROR_NONE
+}
+
+// ParseProcessLogLine parses a single log line with its timestamp as it is found in the
+// log of a process report.
+func ParseProcessLogLine(line [2]string) ProcessLogEntry {
+	entry := ProcessLogEntry{
+		Time: parseLogTime(line[0]),
+		Line: line[1],
+	}
+
+	msg := line[1]
+
+	if m := logComponentRegex.FindStringSubmatch(msg); m != nil {
+		entry.Component = m[1]
+		msg = msg[len(m[0]):]
+	}
+
+	if m := logLevelRegex.FindStringSubmatch(msg); m != nil {
+		entry.Level = m[1]
+		msg = msg[len(m[0]):]
+	}
+
+	entry.Message = strings.TrimSpace(msg)
+	entry.Class = ClassifyProcessError(entry.Message)
+
+	if len(entry.Level) == 0 && entry.Class != PROCESS_ERROR_NONE {
+		entry.Level = "error"
+	}
+
+	return entry
+}
+
+// ParseProcessLog parses all lines of a process log.
+func ParseProcessLog(log [][2]string) []ProcessLogEntry {
+	entries := make([]ProcessLogEntry, 0, len(log))
+
+	for _, line := range log {
+		entries = append(entries, ParseProcessLogLine(line))
+	}
+
+	return entries
+}
+
+func parseLogTime(s string) time.Time {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0)
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+
+	return time.Time{}
+}
+
+// ProcessRunSummary is a summary of a single run of a process.
+type ProcessRunSummary struct {
+	Start    time.Time
+	Duration time.Duration // time between the start and the last log line
+	Current  bool          // whether this is the current run
+	Errors   []ProcessLogEntry
+	Reason   ProcessErrorClass // the probable failure reason, i.e. the class of the last error
+	LastLine string
+}
+
+// SummarizeProcessRun summarizes a single run from a process report.
+func SummarizeProcessRun(entry api.ProcessReportHistoryEntry) ProcessRunSummary {
+	summary := ProcessRunSummary{
+		Start:  time.Unix(entry.CreatedAt, 0),
+		Errors: []ProcessLogEntry{},
+	}
+
+	entries := ParseProcessLog(entry.Log)
+
+	for _, e := range entries {
+		if e.Class != PROCESS_ERROR_NONE {
+			summary.Errors = append(summary.Errors, e)
+		}
+	}
+
+	if len(entries) != 0 {
+		last := entries[len(entries)-1]
+		summary.LastLine = last.Line
+
+		if !last.Time.IsZero() && last.Time.After(summary.Start) {
+			summary.Duration = last.Time.Sub(summary.Start)
+		}
+	}
+
+	// The most specific error class wins over a generic one
+	for i := len(summary.Errors) - 1; i >= 0; i-- {
+		class := summary.Errors[i].Class
+		if class != PROCESS_ERROR_OTHER {
+			summary.Reason = class
+			break
+		}
+
+		if len(summary.Reason) == 0 {
+			summary.Reason = class
+		}
+	}
+
+	return summary
+}
+
+// SummarizeProcessReport summarizes all runs in a process report, starting with the oldest
+// run from the history. The last summary is the current run.
+func SummarizeProcessReport(report api.ProcessReport) []ProcessRunSummary {
+	summaries := make([]ProcessRunSummary, 0, len(report.History)+1)
+
+	for _, h := range report.History {
+		summaries = append(summaries, SummarizeProcessRun(h))
+	}
+
+	if report.CreatedAt != 0 || len(report.Log) != 0 {
+		current := SummarizeProcessRun(report.ProcessReportHistoryEntry)
+		current.Current = true
+		summaries = append(summaries, current)
+	}
+
+	return summaries
+}
@@ -0,0 +1,486 @@
+package coreclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// RestreamerUIMetadataKey is the metadata key where the Restreamer UI stores its settings.
+const RestreamerUIMetadataKey = "restreamer-ui"
+
+// The types in this file model the metadata the Restreamer UI stores with its processes. Fields
+// that are not modelled are kept in Extra and written back unchanged, such that the metadata
+// can be modified from code without breaking the UI. Objects that are absent in the metadata are
+// nil, and fields that are absent are not written back unless they have been set. Parts of the
+// metadata that are only interpreted by the UI itself (e.g. sources and profiles) are kept as
+// raw JSON.
+
+// RestreamerExtra holds the fields of a Restreamer UI metadata object that are not modelled.
+type RestreamerExtra struct {
+	Extra map[string]json.RawMessage
+
+	// present are the names of the fields that have been present when the object has been
+	// unmarshalled. It is nil for objects that have been created in code.
+	present map[string]struct{}
+}
+
+// RestreamerIngest is the metadata of an ingest process ("restreamer-ui:ingest:{channelid}").
+type RestreamerIngest struct {
+	Version  string                   `json:"version"`
+	Meta     *RestreamerMeta          `json:"meta,omitempty"`
+	License  string                   `json:"license"`
+	Sources  json.RawMessage          `json:"sources,omitempty"`
+	Profiles json.RawMessage          `json:"profiles,omitempty"`
+	Streams  json.RawMessage          `json:"streams,omitempty"`
+	Control  *RestreamerIngestControl `json:"control,omitempty"`
+	Player   json.RawMessage          `json:"player,omitempty"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerMeta is the description of a channel.
+type RestreamerMeta struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Author      *RestreamerAuthor `json:"author,omitempty"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerAuthor is the author of a channel.
+type RestreamerAuthor struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerIngestControl are the settings of the outputs of an ingest process.
+type RestreamerIngestControl struct {
+	HLS      *RestreamerHLS      `json:"hls,omitempty"`
+	RTMP     *RestreamerEnable   `json:"rtmp,omitempty"`
+	SRT      *RestreamerEnable   `json:"srt,omitempty"`
+	Process  *RestreamerProcess  `json:"process,omitempty"`
+	Snapshot *RestreamerSnapshot `json:"snapshot,omitempty"`
+	Limits   *RestreamerLimits   `json:"limits,omitempty"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerHLS are the HLS settings of an ingest process.
+type RestreamerHLS struct {
+	LHLS            bool   `json:"lhls"`
+	SegmentDuration int    `json:"segmentDuration"`
+	ListSize        int    `json:"listSize"`
+	Cleanup         bool   `json:"cleanup"`
+	Storage         string `json:"storage"`
+	Version         int    `json:"version"`
+	MasterPlaylist  bool   `json:"master_playlist"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerEnable is a setting that can be enabled or disabled.
+type RestreamerEnable struct {
+	Enable bool `json:"enable"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerSnapshot are the snapshot settings of an ingest process.
+type RestreamerSnapshot struct {
+	Enable   bool `json:"enable"`
+	Interval int  `json:"interval"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerProcess are the process settings as shown in the UI.
+type RestreamerProcess struct {
+	Autostart    bool `json:"autostart"`
+	Reconnect    bool `json:"reconnect"`
+	Delay        int  `json:"delay"`
+	StaleTimeout int  `json:"staleTimeout"`
+	LowDelay     bool `json:"low_delay"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerLimits are the resource limits as shown in the UI.
+type RestreamerLimits struct {
+	CPU     float64 `json:"cpu_usage"`
+	Memory  uint64  `json:"memory_mbytes"`
+	WaitFor uint64  `json:"waitfor_seconds"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerEgress is the metadata of an egress process ("restreamer-ui:egress:{service}:{id}").
+type RestreamerEgress struct {
+	Version  string                   `json:"version"`
+	Name     string                   `json:"name"`
+	Settings json.RawMessage          `json:"settings,omitempty"`
+	Outputs  json.RawMessage          `json:"outputs,omitempty"`
+	Control  *RestreamerEgressControl `json:"control,omitempty"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerEgressControl are the process settings of an egress process.
+type RestreamerEgressControl struct {
+	Process *RestreamerProcess `json:"process,omitempty"`
+	Limits  *RestreamerLimits  `json:"limits,omitempty"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerSettings is the global metadata of the Restreamer UI, stored with MetadataSet. It
+// contains the list of channels the UI shows. The remaining settings are kept as raw JSON or in Extra.
+type RestreamerSettings struct {
+	Bundle     json.RawMessage     `json:"bundle,omitempty"`
+	Playersite json.RawMessage     `json:"playersite,omitempty"`
+	Channels   []RestreamerChannel `json:"channels"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// RestreamerChannel is a channel in the channel list of the Restreamer UI.
+type RestreamerChannel struct {
+	ChannelID string `json:"channelid"`
+	Name      string `json:"name"`
+
+	RestreamerExtra `json:"-"`
+}
+
+// Channel returns the channel with the given ID from the channel list.
+func (m *RestreamerSettings) Channel(channelid string) (RestreamerChannel, bool) {
+	for _, c := range m.Channels {
+		if c.ChannelID == channelid {
+			return c, true
+		}
+	}
+
+	return RestreamerChannel{}, false
+}
+
+// SetChannel adds the channel to the channel list, or renames it if it is already listed. The UI
+// only shows channels that are in this list.
+func (m *RestreamerSettings) SetChannel(channelid, name string) {
+	for i, c := range m.Channels {
+		if c.ChannelID == channelid {
+			m.Channels[i].Name = name
+			return
+		}
+	}
+
+	m.Channels = append(m.Channels, RestreamerChannel{
+		ChannelID: channelid,
+		Name:      name,
+	})
+}
+
+// RemoveChannel removes the channel from the channel list.
+func (m *RestreamerSettings) RemoveChannel(channelid string) {
+	channels := []RestreamerChannel{}
+
+	for _, c := range m.Channels {
+		if c.ChannelID != channelid {
+			channels = append(channels, c)
+		}
+	}
+
+	m.Channels = channels
+}
+
+func (m *RestreamerIngest) UnmarshalJSON(data []byte) error {
+	type alias RestreamerIngest
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerIngest) MarshalJSON() ([]byte, error) {
+	type alias RestreamerIngest
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerMeta) UnmarshalJSON(data []byte) error {
+	type alias RestreamerMeta
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerMeta) MarshalJSON() ([]byte, error) {
+	type alias RestreamerMeta
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerAuthor) UnmarshalJSON(data []byte) error {
+	type alias RestreamerAuthor
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerAuthor) MarshalJSON() ([]byte, error) {
+	type alias RestreamerAuthor
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerIngestControl) UnmarshalJSON(data []byte) error {
+	type alias RestreamerIngestControl
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerIngestControl) MarshalJSON() ([]byte, error) {
+	type alias RestreamerIngestControl
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerHLS) UnmarshalJSON(data []byte) error {
+	type alias RestreamerHLS
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerHLS) MarshalJSON() ([]byte, error) {
+	type alias RestreamerHLS
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerEnable) UnmarshalJSON(data []byte) error {
+	type alias RestreamerEnable
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerEnable) MarshalJSON() ([]byte, error) {
+	type alias RestreamerEnable
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerSnapshot) UnmarshalJSON(data []byte) error {
+	type alias RestreamerSnapshot
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerSnapshot) MarshalJSON() ([]byte, error) {
+	type alias RestreamerSnapshot
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerProcess) UnmarshalJSON(data []byte) error {
+	type alias RestreamerProcess
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerProcess) MarshalJSON() ([]byte, error) {
+	type alias RestreamerProcess
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerLimits) UnmarshalJSON(data []byte) error {
+	type alias RestreamerLimits
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerLimits) MarshalJSON() ([]byte, error) {
+	type alias RestreamerLimits
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerEgress) UnmarshalJSON(data []byte) error {
+	type alias RestreamerEgress
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerEgress) MarshalJSON() ([]byte, error) {
+	type alias RestreamerEgress
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerEgressControl) UnmarshalJSON(data []byte) error {
+	type alias RestreamerEgressControl
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerEgressControl) MarshalJSON() ([]byte, error) {
+	type alias RestreamerEgressControl
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerSettings) UnmarshalJSON(data []byte) error {
+	type alias RestreamerSettings
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerSettings) MarshalJSON() ([]byte, error) {
+	type alias RestreamerSettings
+
+	// The UI expects a list, even if it is empty
+	if m.Channels == nil {
+		m.Channels = []RestreamerChannel{}
+	}
+
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+func (m *RestreamerChannel) UnmarshalJSON(data []byte) error {
+	type alias RestreamerChannel
+	return unmarshalWithExtra(data, (*alias)(m), &m.RestreamerExtra)
+}
+
+func (m RestreamerChannel) MarshalJSON() ([]byte, error) {
+	type alias RestreamerChannel
+	return marshalWithExtra(alias(m), m.RestreamerExtra)
+}
+
+// unmarshalWithExtra unmarshals the data into v, stores all fields that are not known
+// to v in the extra fields and remembers which fields have been present.
+func unmarshalWithExtra(data []byte, v interface{}, extra *RestreamerExtra) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extra.present = map[string]struct{}{}
+	for name := range fields {
+		extra.present[name] = struct{}{}
+	}
+
+	for _, name := range jsonFieldNames(reflect.TypeOf(v).Elem()) {
+		delete(fields, name)
+	}
+
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	extra.Extra = fields
+
+	return nil
+}
+
+// marshalWithExtra marshals v and adds the extra fields that are not known to v. Known fields that
+// have been absent when v has been unmarshalled are only written if they are not zero.
+func marshalWithExtra(v interface{}, extra RestreamerExtra) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || (len(extra.Extra) == 0 && extra.present == nil) {
+		return data, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if extra.present != nil {
+		for name, value := range fields {
+			if _, ok := extra.present[name]; !ok && isZeroJSON(value) {
+				delete(fields, name)
+			}
+		}
+	}
+
+	for name, value := range extra.Extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+func isZeroJSON(value json.RawMessage) bool {
+	for _, zero := range []string{`""`, `0`, `false`, `null`} {
+		if bytes.Equal(value, []byte(zero)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	names := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+
+		if len(tag) == 0 {
+			tag = t.Field(i).Name
+		}
+
+		names = append(names, tag)
+	}
+
+	return names
+}
+
+// RestreamerSettingsMetadata returns the global Restreamer UI metadata. If the UI didn't store
+// any settings yet, empty settings are returned.
+func RestreamerSettingsMetadata(client RestClient) (RestreamerSettings, error) {
+	m := RestreamerSettings{}
+
+	data, err := client.Metadata(RestreamerUIMetadataKey)
+	if err != nil {
+		if isNotFound(err) {
+			return m, nil
+		}
+
+		return m, err
+	}
+
+	if data == nil {
+		return m, nil
+	}
+
+	err = decodeMetadata(data, &m)
+
+	return m, err
+}
+
+// RestreamerSettingsMetadataSet stores the global Restreamer UI metadata.
+func RestreamerSettingsMetadataSet(client RestClient, m RestreamerSettings) error {
+	return client.MetadataSet(RestreamerUIMetadataKey, m)
+}
+
+// RestreamerIngestMetadata returns the Restreamer UI metadata of the ingest process with the given ID.
+func RestreamerIngestMetadata(client RestClient, id string) (RestreamerIngest, error) {
+	m := RestreamerIngest{}
+
+	data, err := client.ProcessMetadata(id, RestreamerUIMetadataKey)
+	if err != nil {
+		return m, err
+	}
+
+	err = decodeMetadata(data, &m)
+
+	return m, err
+}
+
+// RestreamerIngestMetadataSet stores the Restreamer UI metadata of the ingest process with the given ID.
+func RestreamerIngestMetadataSet(client RestClient, id string, m RestreamerIngest) error {
+	return client.ProcessMetadataSet(id, RestreamerUIMetadataKey, m)
+}
+
+// RestreamerEgressMetadata returns the Restreamer UI metadata of the egress process with the given ID.
+func RestreamerEgressMetadata(client RestClient, id string) (RestreamerEgress, error) {
+	m := RestreamerEgress{}
+
+	data, err := client.ProcessMetadata(id, RestreamerUIMetadataKey)
+	if err != nil {
+		return m, err
+	}
+
+	err = decodeMetadata(data, &m)
+
+	return m, err
+}
+
+// RestreamerEgressMetadataSet stores the Restreamer UI metadata of the egress process with the given ID.
+func RestreamerEgressMetadataSet(client RestClient, id string, m RestreamerEgress) error {
+	return client.ProcessMetadataSet(id, RestreamerUIMetadataKey, m)
+}
@@ -0,0 +1,235 @@
+package coreclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ProgressIORates are the rates derived from the counters of an input or output stream.
+type ProgressIORates struct {
+	ID         string
+	Address    string
+	Index      uint64
+	Stream     uint64
+	Type       string
+	Bitrate    float64 // kbit/s
+	PacketRate float64 // packets/s
+	FrameRate  float64 // frames/s
+}
+
+// ProgressRates are the rates derived from the counters of successive progress samples.
+type ProgressRates struct {
+	Duration   time.Duration // time span the rates are computed over
+	Samples    int
+	FrameRate  float64 // frames/s
+	PacketRate float64 // packets/s
+	Bitrate    float64 // kbit/s
+	DropRate   float64 // dropped frames/s
+	DupRate    float64 // duplicated frames/s
+	Realtime   float64 // processed media time per wall clock time
+	Input      []ProgressIORates
+	Output     []ProgressIORates
+}
+
+type progressSample struct {
+	time    time.Time
+	runtime int64
+	*api.Progress
+}
+
+// ProgressSampler keeps a rolling window of progress samples per process and derives rates
+// from their cumulative counters. A restart of a process, i.e. a reset of the counters,
+// discards the samples before the restart.
+type ProgressSampler struct {
+	window int
+
+	lock    sync.RWMutex
+	samples map[string][]progressSample
+}
+
+// NewProgressSampler returns a new sampler that keeps up to window samples per process.
+// The window is at least 2.
+func NewProgressSampler(window int) *ProgressSampler {
+	if window < 2 {
+		window = 2
+	}
+
+	return &ProgressSampler{
+		window:  window,
+		samples: map[string][]progressSample{},
+	}
+}
+
+// Add adds a sample of the state of the process with the given ID, taken now.
+func (s *ProgressSampler) Add(id string, state api.ProcessState) {
+	s.AddAt(id, time.Now(), state)
+}
+
+// AddAt adds a sample of the state of the process with the given ID, taken at the given time.
+func (s *ProgressSampler) AddAt(id string, t time.Time, state api.ProcessState) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if state.Progress == nil || state.State != "running" {
+		delete(s.samples, id)
+		return
+	}
+
+	sample := progressSample{
+		time:     t,
+		runtime:  state.Runtime,
+		Progress: state.Progress,
+	}
+
+	samples := s.samples[id]
+
+	if len(samples) != 0 && isCounterReset(samples[len(samples)-1], sample) {
+		samples = nil
+	}
+
+	samples = append(samples, sample)
+	if len(samples) > s.window {
+		samples = samples[len(samples)-s.window:]
+	}
+
+	s.samples[id] = samples
+}
+
+// Remove removes all samples of the process with the given ID.
+func (s *ProgressSampler) Remove(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.samples, id)
+}
+
+// Sample fetches the state of all processes selected by opts and adds them as samples.
+// Processes that are not in the list anymore are removed.
+func (s *ProgressSampler) Sample(client RestClient, opts ProcessListOptions) error {
+	opts.Filter = []string{"state"}
+
+	processes, err := client.ProcessList(opts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	ids := map[string]struct{}{}
+
+	for _, p := range processes {
+		if p.State == nil {
+			continue
+		}
+
+		ids[p.ID] = struct{}{}
+		s.AddAt(p.ID, now, *p.State)
+	}
+
+	s.lock.Lock()
+	for id := range s.samples {
+		if _, ok := ids[id]; !ok {
+			delete(s.samples, id)
+		}
+	}
+	s.lock.Unlock()
+
+	return nil
+}
+
+// Rates returns the rates of the process with the given ID derived from the oldest and the
+// most recent sample in the window. It returns false if less than two samples are available.
+func (s *ProgressSampler) Rates(id string) (ProgressRates, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	rates := ProgressRates{}
+
+	samples := s.samples[id]
+	if len(samples) < 2 {
+		return rates, false
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+
+	dt := last.time.Sub(first.time).Seconds()
+	if dt <= 0 {
+		return rates, false
+	}
+
+	rates.Duration = last.time.Sub(first.time)
+	rates.Samples = len(samples)
+	rates.FrameRate = float64(last.Frame-first.Frame) / dt
+	rates.PacketRate = float64(last.Packet-first.Packet) / dt
+	rates.Bitrate = kbit(last.Size-first.Size) / dt
+	rates.DropRate = float64(last.Drop-first.Drop) / dt
+	rates.DupRate = float64(last.Dup-first.Dup) / dt
+	rates.Realtime = (last.Time - first.Time) / dt
+	rates.Input = ioRates(first.Input, last.Input, dt)
+	rates.Output = ioRates(first.Output, last.Output, dt)
+
+	return rates, true
+}
+
+func ioRates(first, last []api.ProgressIO, dt float64) []ProgressIORates {
+	rates := []ProgressIORates{}
+
+	for _, l := range last {
+		for _, f := range first {
+			if f.ID != l.ID || f.Index != l.Index || f.Stream != l.Stream {
+				continue
+			}
+
+			rates = append(rates, ProgressIORates{
+				ID:         l.ID,
+				Address:    l.Address,
+				Index:      l.Index,
+				Stream:     l.Stream,
+				Type:       l.Type,
+				Bitrate:    kbit(l.Size-f.Size) / dt,
+				PacketRate: float64(l.Packet-f.Packet) / dt,
+				FrameRate:  float64(l.Frame-f.Frame) / dt,
+			})
+
+			break
+		}
+	}
+
+	return rates
+}
+
+// kbit converts a size in KiB, as reported by the core, to kbit.
+func kbit(size uint64) float64 {
+	return float64(size) * 8 * 1024 / 1000
+}
+
+// isCounterReset returns whether any of the counters decreased between two samples,
+// e.g. because the process has been restarted.
+func isCounterReset(prev, curr progressSample) bool {
+	if curr.runtime < prev.runtime {
+		return true
+	}
+
+	if curr.Frame < prev.Frame || curr.Packet < prev.Packet || curr.Size < prev.Size || curr.Drop < prev.Drop || curr.Dup < prev.Dup || curr.Time < prev.Time {
+		return true
+	}
+
+	if len(curr.Input) != len(prev.Input) || len(curr.Output) != len(prev.Output) {
+		return true
+	}
+
+	for i := range curr.Input {
+		if curr.Input[i].Size < prev.Input[i].Size || curr.Input[i].Packet < prev.Input[i].Packet || curr.Input[i].Frame < prev.Input[i].Frame {
+			return true
+		}
+	}
+
+	for i := range curr.Output {
+		if curr.Output[i].Size < prev.Output[i].Size || curr.Output[i].Packet < prev.Output[i].Packet || curr.Output[i].Frame < prev.Output[i].Frame {
+			return true
+		}
+	}
+
+	return false
+}
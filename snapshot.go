@@ -0,0 +1,212 @@
+package coreclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// SnapshotOptions are the options for the snapshot helpers. Either ProcessID or Address is required.
+type SnapshotOptions struct {
+	// ProcessID is the ID of the process whose first input will be used as the source.
+	ProcessID string
+
+	// Address is the address of the source, if no ProcessID is given.
+	Address string
+
+	// Options are the ffmpeg options for the source, if no ProcessID is given.
+	Options []string
+
+	// Width is the width of the image. The aspect ratio is kept. 0 keeps the original size.
+	Width uint
+
+	// Timeout is the max. time to wait for the image. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// source returns the input for the snapshot process.
+func (o SnapshotOptions) source(client RestClient) (api.ProcessConfigIO, error) {
+	if len(o.ProcessID) == 0 {
+		if len(o.Address) == 0 {
+			return api.ProcessConfigIO{}, fmt.Errorf("either a process ID or an address is required")
+		}
+
+		return api.ProcessConfigIO{
+			ID:      "input_0",
+			Address: o.Address,
+			Options: append([]string{}, o.Options...),
+		}, nil
+	}
+
+	config, err := client.ProcessConfig(o.ProcessID)
+	if err != nil {
+		return api.ProcessConfigIO{}, err
+	}
+
+	if len(config.Input) == 0 {
+		return api.ProcessConfigIO{}, fmt.Errorf("process %s has no inputs", o.ProcessID)
+	}
+
+	input := config.Input[0]
+	input.Options = append([]string{}, input.Options...)
+	input.Cleanup = nil
+
+	return input, nil
+}
+
+func snapshotOutput(path string, width uint, extra ...string) api.ProcessConfigIO {
+	options := []string{}
+
+	if width != 0 {
+		options = append(options, "-vf", "scale="+strconv.FormatUint(uint64(width), 10)+":-2")
+	}
+
+	options = append(options, extra...)
+	options = append(options, "-f", "image2", "-update", "1")
+
+	return api.ProcessConfigIO{
+		ID:      "output_0",
+		Address: "{memfs}" + path,
+		Options: options,
+		Cleanup: []api.ProcessConfigIOCleanup{
+			{
+				Pattern:       "memfs:" + path,
+				PurgeOnDelete: true,
+			},
+		},
+	}
+}
+
+// Snapshot captures a single JPEG image of the source. It creates a short-lived process that writes
+// the image to the mem filesystem, waits for the file, returns its content and cleans up the process
+// and the file, even if capturing the image fails.
+func Snapshot(ctx context.Context, client RestClient, opts SnapshotOptions) ([]byte, error) {
+	input, err := opts.source(client)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	id := "snapshot-" + randomID()
+	path := "/" + id + ".jpg"
+
+	config := api.ProcessConfig{
+		ID:        id,
+		Type:      "ffmpeg",
+		Options:   []string{"-err_detect", "ignore_err"},
+		Input:     []api.ProcessConfigIO{input},
+		Output:    []api.ProcessConfigIO{snapshotOutput(path, opts.Width, "-frames:v", "1")},
+		Reconnect: false,
+		Autostart: true,
+	}
+
+	if err := client.ProcessAdd(config); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		client.ProcessCommand(id, "stop")
+		client.ProcessDelete(id)
+		client.MemFSDeleteFile(path)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	err = poll(ctx, func() (bool, error) {
+		if client.MemFSHasFile(path) {
+			return true, nil
+		}
+
+		state, err := client.ProcessState(id)
+		if err != nil {
+			return false, err
+		}
+
+		if state.State == "failed" {
+			return false, StateError{ID: id, State: state.State, LastLog: state.LastLog}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readMemFSFile(client, path)
+}
+
+func readMemFSFile(client RestClient, path string) ([]byte, error) {
+	data, err := client.MemFSGetFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer data.Close()
+
+	return io.ReadAll(data)
+}
+
+// RollingSnapshot is a process that continuously updates a snapshot of its source.
+type RollingSnapshot struct {
+	ID   string // ID of the snapshot process
+	Path string // path of the image on the mem filesystem
+}
+
+// Get returns the current image of the rolling snapshot.
+func (r RollingSnapshot) Get(client RestClient) ([]byte, error) {
+	return readMemFSFile(client, r.Path)
+}
+
+// Remove stops and deletes the snapshot process. The image is removed with the process.
+func (r RollingSnapshot) Remove(client RestClient) error {
+	if err := client.ProcessCommand(r.ID, "stop"); err != nil {
+		return err
+	}
+
+	return client.ProcessDelete(r.ID)
+}
+
+// EnsureRollingSnapshot creates or updates a process with the given ID that keeps running and
+// updates an image of the source on the mem filesystem in the given interval. The process is
+// only updated if its config changed.
+func EnsureRollingSnapshot(client RestClient, id string, interval time.Duration, opts SnapshotOptions) (RollingSnapshot, error) {
+	r := RollingSnapshot{
+		ID:   id,
+		Path: "/" + id + ".jpg",
+	}
+
+	input, err := opts.source(client)
+	if err != nil {
+		return r, err
+	}
+
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	output := snapshotOutput(r.Path, opts.Width, "-r", "1/"+strconv.FormatInt(int64(interval.Seconds()), 10))
+
+	config := api.ProcessConfig{
+		ID:             id,
+		Type:           "ffmpeg",
+		Options:        []string{"-err_detect", "ignore_err"},
+		Input:          []api.ProcessConfigIO{input},
+		Output:         []api.ProcessConfigIO{output},
+		Reconnect:      true,
+		ReconnectDelay: uint64(interval.Seconds()),
+		Autostart:      true,
+		StaleTimeout:   30,
+	}
+
+	_, err = ProcessUpsert(client, config)
+
+	return r, err
+}
@@ -0,0 +1,185 @@
+package coreclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// Stability is the classification of the run history of a process.
+type Stability string
+
+const (
+	STABILITY_HEALTHY      Stability = "healthy"
+	STABILITY_FLAPPING     Stability = "flapping"
+	STABILITY_CRASHLOOPING Stability = "crashlooping"
+)
+
+// StabilityConfig are the thresholds for classifying the stability of a process.
+type StabilityConfig struct {
+	// Window is the time span of the history that is considered. Defaults to 1 hour.
+	Window time.Duration
+
+	// ShortRun is the max. duration of a run that is considered a crash. Defaults to 1 minute.
+	ShortRun time.Duration
+
+	// CrashLoopRuns is the number of consecutive short runs within the window, including the
+	// most recent one, that classifies a process as crash-looping. Defaults to 3.
+	CrashLoopRuns int
+
+	// FlappingRuns is the number of runs within the window that classifies a process as
+	// flapping. Defaults to 4.
+	FlappingRuns int
+}
+
+func (c StabilityConfig) withDefaults() StabilityConfig {
+	if c.Window <= 0 {
+		c.Window = time.Hour
+	}
+
+	if c.ShortRun <= 0 {
+		c.ShortRun = time.Minute
+	}
+
+	if c.CrashLoopRuns <= 0 {
+		c.CrashLoopRuns = 3
+	}
+
+	if c.FlappingRuns <= 0 {
+		c.FlappingRuns = 4
+	}
+
+	return c
+}
+
+// ProcessStability is the result of the stability analysis of a process.
+type ProcessStability struct {
+	ID        string
+	Reference string
+	Stability Stability
+	State     string            // the current exec state
+	Runs      int               // number of runs within the window
+	ShortRuns int               // number of consecutive short runs within the window, including the most recent one
+	Reason    ProcessErrorClass // the probable failure reason of the most recent failed run
+	LastLog   string
+}
+
+func (p ProcessStability) String() string {
+	return fmt.Sprintf("%s: %s (state: %s, runs: %d, short runs: %d, reason: %s)", p.ID, p.Stability, p.State, p.Runs, p.ShortRuns, p.Reason)
+}
+
+// AnalyzeProcessStability classifies a process as healthy, flapping or crash-looping based on the
+// runs in its report and its current state. A process is only crash-looping if it is failing or
+// ordered to start.
+func AnalyzeProcessStability(report api.ProcessReport, state api.ProcessState, config StabilityConfig) ProcessStability {
+	config = config.withDefaults()
+	now := time.Now()
+
+	result := ProcessStability{
+		Stability: STABILITY_HEALTHY,
+		State:     state.State,
+		LastLog:   state.LastLog,
+	}
+
+	runs := SummarizeProcessReport(report)
+
+	// The duration of a finished run is bounded by the start of the next run
+	for i := 0; i < len(runs)-1; i++ {
+		if next := runs[i+1].Start.Sub(runs[i].Start); runs[i].Duration == 0 || next < runs[i].Duration {
+			runs[i].Duration = next
+		}
+	}
+
+	if len(runs) != 0 && runs[len(runs)-1].Current && state.State == "running" {
+		runs[len(runs)-1].Duration = time.Duration(state.Runtime) * time.Second
+	}
+
+	for _, r := range runs {
+		if now.Sub(r.Start) <= config.Window {
+			result.Runs++
+		}
+	}
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+
+		// Crashes outside of the window don't count towards a crash loop
+		if now.Sub(r.Start) > config.Window {
+			break
+		}
+
+		if r.Current && state.State == "running" && r.Duration < config.ShortRun {
+			// The current run is still young, it doesn't count as crashed yet
+			continue
+		}
+
+		if r.Duration >= config.ShortRun {
+			break
+		}
+
+		result.ShortRuns++
+
+		if len(result.Reason) == 0 {
+			result.Reason = r.Reason
+		}
+	}
+
+	// A process that has been stopped on purpose isn't crash-looping, even if its last runs were short
+	failing := state.State == "failed" || state.Reconnect > 0
+	restarting := state.Order == "start" && state.Runtime < int64(config.ShortRun.Seconds())
+
+	if result.ShortRuns >= config.CrashLoopRuns && (failing || restarting) {
+		result.Stability = STABILITY_CRASHLOOPING
+	} else if result.Runs >= config.FlappingRuns {
+		result.Stability = STABILITY_FLAPPING
+	}
+
+	return result
+}
+
+// FleetStability is the result of the stability analysis of many processes.
+type FleetStability struct {
+	Processes []ProcessStability
+}
+
+// Misbehaving returns all processes that are not healthy.
+func (f FleetStability) Misbehaving() []ProcessStability {
+	m := []ProcessStability{}
+
+	for _, p := range f.Processes {
+		if p.Stability != STABILITY_HEALTHY {
+			m = append(m, p)
+		}
+	}
+
+	return m
+}
+
+// AnalyzeFleetStability analyzes the stability of all processes selected by opts.
+func AnalyzeFleetStability(client RestClient, opts ProcessListOptions, config StabilityConfig) (FleetStability, error) {
+	fleet := FleetStability{
+		Processes: []ProcessStability{},
+	}
+
+	opts.Filter = []string{"state", "report"}
+
+	processes, err := client.ProcessList(opts)
+	if err != nil {
+		return fleet, err
+	}
+
+	for _, p := range processes {
+		if p.State == nil || p.Report == nil {
+			continue
+		}
+
+		s := AnalyzeProcessStability(*p.Report, *p.State, config)
+		s.ID = p.ID
+		s.Reference = p.Reference
+
+		fleet.Processes = append(fleet.Processes, s)
+	}
+
+	return fleet, nil
+}
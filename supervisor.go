@@ -0,0 +1,306 @@
+package coreclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// SupervisorMetadataKey is the process metadata key where the supervisor records its interventions.
+const SupervisorMetadataKey = "supervisor"
+
+// SupervisorPolicy describes how the supervisor reacts to a failed process.
+type SupervisorPolicy struct {
+	// InitialDelay is the delay before the first restart. Defaults to 1 second.
+	InitialDelay time.Duration
+
+	// MaxDelay is the upper bound for the delay between restarts. Defaults to 5 minutes.
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay grows with each restart in the window. Defaults to 2.
+	Multiplier float64
+
+	// MaxRestarts is the max. number of restarts within Window before the supervisor gives up
+	// and stops the process. 0 means no limit. Restarts that are older than Window are forgotten,
+	// such that the delay between restarts starts again at InitialDelay. Window defaults to 10 minutes.
+	MaxRestarts int
+	Window      time.Duration
+
+	// Fallback is an input that replaces the input with the same ID (or the first input, if no
+	// ID matches) after EscalateAfter restarts within Window. The process is updated with
+	// ProcessUpdate. Optional.
+	Fallback      *api.ProcessConfigIO
+	EscalateAfter int
+
+	// OnGiveUp is called after the supervisor stopped a process because of too many restarts. Optional.
+	OnGiveUp func(id, reason string)
+}
+
+// SupervisorConfig is the configuration for a new supervisor.
+type SupervisorConfig struct {
+	// Select selects the processes to supervise. These processes should have Reconnect disabled,
+	// otherwise the core will restart them as well.
+	Select ProcessListOptions
+
+	// Interval is the time between two checks of the processes. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// Policy is the policy applied to all supervised processes.
+	Policy SupervisorPolicy
+
+	// History is the number of interventions kept in the process metadata. Defaults to 50.
+	History int
+}
+
+// SupervisorIntervention is a record of an action the supervisor took on a process.
+type SupervisorIntervention struct {
+	Time    int64  `json:"time"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason"`
+	Attempt int    `json:"attempt"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Supervisor watches processes and restarts them according to a SupervisorPolicy if they failed.
+type Supervisor struct {
+	client RestClient
+	config SupervisorConfig
+
+	lock     sync.Mutex
+	restarts map[string][]time.Time
+	pending  map[string]bool
+}
+
+// NewSupervisor returns a new supervisor for the given client and config.
+func NewSupervisor(client RestClient, config SupervisorConfig) *Supervisor {
+	if config.Policy.InitialDelay <= 0 {
+		config.Policy.InitialDelay = time.Second
+	}
+
+	if config.Policy.MaxDelay <= 0 {
+		config.Policy.MaxDelay = 5 * time.Minute
+	}
+
+	if config.Policy.Multiplier < 1 {
+		config.Policy.Multiplier = 2
+	}
+
+	if config.Policy.Window <= 0 {
+		config.Policy.Window = 10 * time.Minute
+	}
+
+	if config.History <= 0 {
+		config.History = 50
+	}
+
+	return &Supervisor{
+		client:   client,
+		config:   config,
+		restarts: map[string][]time.Time{},
+		pending:  map[string]bool{},
+	}
+}
+
+// Run supervises the processes until the context is done. A process that has been
+// ordered to start but is failed will be restarted according to the policy.
+func (s *Supervisor) Run(ctx context.Context) {
+	interval := s.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	list := s.config.Select
+	list.Filter = []string{"state"}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	wg := sync.WaitGroup{}
+
+	for {
+		processes, err := s.client.ProcessList(list)
+		if err == nil {
+			ids := map[string]struct{}{}
+
+			for _, p := range processes {
+				ids[p.ID] = struct{}{}
+
+				if p.State == nil || p.State.Order != "start" || p.State.State != "failed" {
+					continue
+				}
+
+				wg.Add(1)
+				go func(id, reason string) {
+					defer wg.Done()
+					s.intervene(ctx, id, reason)
+				}(p.ID, p.State.LastLog)
+			}
+
+			s.lock.Lock()
+			for id := range s.restarts {
+				if _, ok := ids[id]; !ok {
+					delete(s.restarts, id)
+				}
+			}
+			s.lock.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Supervisor) intervene(ctx context.Context, id, reason string) {
+	policy := s.config.Policy
+	now := time.Now()
+
+	s.lock.Lock()
+	if s.pending[id] {
+		s.lock.Unlock()
+		return
+	}
+
+	restarts := []time.Time{}
+	for _, t := range s.restarts[id] {
+		if now.Sub(t) <= policy.Window {
+			restarts = append(restarts, t)
+		}
+	}
+
+	attempt := len(restarts) + 1
+
+	if policy.MaxRestarts > 0 && attempt > policy.MaxRestarts {
+		s.restarts[id] = restarts
+		s.lock.Unlock()
+
+		err := s.client.ProcessCommand(id, "stop")
+		s.record(id, "give_up", reason, attempt, err)
+
+		if policy.OnGiveUp != nil {
+			policy.OnGiveUp(id, reason)
+		}
+
+		return
+	}
+
+	s.restarts[id] = append(restarts, now)
+	s.pending[id] = true
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.pending, id)
+		s.lock.Unlock()
+	}()
+
+	delay := float64(policy.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= policy.Multiplier
+		if delay > float64(policy.MaxDelay) {
+			delay = float64(policy.MaxDelay)
+			break
+		}
+	}
+
+	timer := time.NewTimer(time.Duration(delay))
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return
+	case <-timer.C:
+	}
+
+	if policy.Fallback != nil && policy.EscalateAfter > 0 && attempt > policy.EscalateAfter {
+		err := s.escalate(id)
+		s.record(id, "fallback", reason, attempt, err)
+		if err == nil {
+			return
+		}
+	}
+
+	err := s.client.ProcessCommand(id, "stop")
+	if err == nil {
+		err = s.client.ProcessCommand(id, "start")
+	}
+
+	s.record(id, "restart", reason, attempt, err)
+}
+
+// escalate replaces the input of the process with the fallback input.
+func (s *Supervisor) escalate(id string) error {
+	config, err := s.client.ProcessConfig(id)
+	if err != nil {
+		return err
+	}
+
+	if len(config.Input) == 0 {
+		return fmt.Errorf("process has no inputs")
+	}
+
+	fallback := *s.config.Policy.Fallback
+
+	index := 0
+	for i, input := range config.Input {
+		if input.ID == fallback.ID {
+			index = i
+			break
+		}
+	}
+
+	if len(fallback.ID) == 0 {
+		fallback.ID = config.Input[index].ID
+	}
+
+	if config.Input[index].Address == fallback.Address {
+		return fmt.Errorf("fallback input is already in use")
+	}
+
+	config.Input[index] = fallback
+
+	return s.client.ProcessUpdate(id, config)
+}
+
+// record appends the intervention to the interventions stored in the process metadata.
+func (s *Supervisor) record(id, action, reason string, attempt int, err error) {
+	intervention := SupervisorIntervention{
+		Time:    time.Now().Unix(),
+		Action:  action,
+		Reason:  reason,
+		Attempt: attempt,
+	}
+
+	if err != nil {
+		intervention.Error = err.Error()
+	}
+
+	history := []SupervisorIntervention{}
+
+	if m, err := s.client.ProcessMetadata(id, SupervisorMetadataKey); err == nil && m != nil {
+		decodeMetadata(m, &history)
+	}
+
+	history = append(history, intervention)
+	if len(history) > s.config.History {
+		history = history[len(history)-s.config.History:]
+	}
+
+	s.client.ProcessMetadataSet(id, SupervisorMetadataKey, history)
+}
+
+// decodeMetadata converts the generic metadata into the given value.
+func decodeMetadata(m api.Metadata, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
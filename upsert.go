@@ -0,0 +1,106 @@
+package coreclient
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// UpsertResult describes what ProcessUpsert did with a process.
+type UpsertResult string
+
+const (
+	UPSERT_CREATED   UpsertResult = "created"
+	UPSERT_UPDATED   UpsertResult = "updated"
+	UPSERT_UNCHANGED UpsertResult = "unchanged"
+)
+
+// ProcessUpsert creates the process described by config if it doesn't exist yet. If it exists,
+// the process will only be updated if the normalized config differs from the current config
+// on the core. This avoids restarting processes where nothing changed.
+func ProcessUpsert(client RestClient, config api.ProcessConfig) (UpsertResult, error) {
+	current, err := client.ProcessConfig(config.ID)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+
+		if err := client.ProcessAdd(config); err != nil {
+			return "", err
+		}
+
+		return UPSERT_CREATED, nil
+	}
+
+	if ProcessConfigEqual(current, config) {
+		return UPSERT_UNCHANGED, nil
+	}
+
+	if err := client.ProcessUpdate(config.ID, config); err != nil {
+		return "", err
+	}
+
+	return UPSERT_UPDATED, nil
+}
+
+// ProcessConfigEqual returns whether two process configs are equal after normalizing
+// them, i.e. after applying the same defaults as the core does.
+func ProcessConfigEqual(a, b api.ProcessConfig) bool {
+	return reflect.DeepEqual(NormalizeProcessConfig(a), NormalizeProcessConfig(b))
+}
+
+// NormalizeProcessConfig returns a copy of the config with the defaults of the core applied
+// and with empty lists represented the same way. Inputs and outputs without an ID get the ID
+// the core assigns to them, i.e. "input_{index}" and "output_{index}".
+func NormalizeProcessConfig(config api.ProcessConfig) api.ProcessConfig {
+	c := config
+
+	if len(c.Type) == 0 {
+		c.Type = "ffmpeg"
+	}
+
+	c.Options = normalizeStrings(c.Options)
+	c.Input = normalizeIO(c.Input, "input_")
+	c.Output = normalizeIO(c.Output, "output_")
+
+	return c
+}
+
+func normalizeIO(ios []api.ProcessConfigIO, prefix string) []api.ProcessConfigIO {
+	n := make([]api.ProcessConfigIO, 0, len(ios))
+
+	for i, io := range ios {
+		if len(io.ID) == 0 {
+			io.ID = prefix + strconv.Itoa(i)
+		}
+
+		io.Options = normalizeStrings(io.Options)
+
+		if len(io.Cleanup) == 0 {
+			io.Cleanup = nil
+		} else {
+			io.Cleanup = append([]api.ProcessConfigIOCleanup(nil), io.Cleanup...)
+		}
+
+		n = append(n, io)
+	}
+
+	return n
+}
+
+func normalizeStrings(s []string) []string {
+	return append([]string{}, s...)
+}
+
+// isNotFound returns whether the error is an API error with the status code 404.
+func isNotFound(err error) bool {
+	var e api.Error
+	if errors.As(err, &e) {
+		return e.Code == http.StatusNotFound
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package coreclient
+
+import (
+	"testing"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// upsertTestClient is a client that only implements the calls used by ProcessUpsert.
+type upsertTestClient struct {
+	RestClient
+
+	config  api.ProcessConfig
+	updates int
+}
+
+func (c *upsertTestClient) ProcessConfig(id string) (api.ProcessConfig, error) {
+	return c.config, nil
+}
+
+func (c *upsertTestClient) ProcessUpdate(id string, p api.ProcessConfig) error {
+	c.updates++
+	return nil
+}
+
+func upsertDesiredConfig() api.ProcessConfig {
+	return api.ProcessConfig{
+		ID: "stream",
+		Input: []api.ProcessConfigIO{
+			{Address: "rtmp://localhost/live/in", Options: []string{"-re"}},
+		},
+		Output: []api.ProcessConfigIO{
+			{Address: "{memfs}/stream.m3u8", Options: []string{"-f", "hls"}},
+			{ID: "archive", Address: "{diskfs}/stream.mp4"},
+		},
+		Reconnect:      true,
+		ReconnectDelay: 10,
+		Autostart:      true,
+	}
+}
+
+// upsertCoreConfig is the desired config as the core returns it.
+func upsertCoreConfig() api.ProcessConfig {
+	return api.ProcessConfig{
+		ID:      "stream",
+		Type:    "ffmpeg",
+		Options: []string{},
+		Input: []api.ProcessConfigIO{
+			{ID: "input_0", Address: "rtmp://localhost/live/in", Options: []string{"-re"}, Cleanup: []api.ProcessConfigIOCleanup{}},
+		},
+		Output: []api.ProcessConfigIO{
+			{ID: "output_0", Address: "{memfs}/stream.m3u8", Options: []string{"-f", "hls"}, Cleanup: []api.ProcessConfigIOCleanup{}},
+			{ID: "archive", Address: "{diskfs}/stream.mp4", Options: []string{}, Cleanup: []api.ProcessConfigIOCleanup{}},
+		},
+		Reconnect:      true,
+		ReconnectDelay: 10,
+		Autostart:      true,
+	}
+}
+
+func TestProcessConfigEqual(t *testing.T) {
+	if !ProcessConfigEqual(upsertCoreConfig(), upsertDesiredConfig()) {
+		t.Errorf("expected the config returned by the core to equal the desired config")
+	}
+
+	changed := upsertDesiredConfig()
+	changed.Output[0].Options = []string{"-f", "hls", "-hls_time", "4"}
+
+	if ProcessConfigEqual(upsertCoreConfig(), changed) {
+		t.Errorf("expected a changed output to differ")
+	}
+
+	renamed := upsertDesiredConfig()
+	renamed.Output[1].ID = "output_1"
+
+	if ProcessConfigEqual(upsertCoreConfig(), renamed) {
+		t.Errorf("expected a changed output ID to differ")
+	}
+}
+
+func TestProcessUpsertUnchanged(t *testing.T) {
+	client := &upsertTestClient{config: upsertCoreConfig()}
+
+	result, err := ProcessUpsert(client, upsertDesiredConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result != UPSERT_UNCHANGED || client.updates != 0 {
+		t.Errorf("expected the process to be unchanged, got %s with %d updates", result, client.updates)
+	}
+
+	changed := upsertDesiredConfig()
+	changed.ReconnectDelay = 20
+
+	result, err = ProcessUpsert(client, changed)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result != UPSERT_UPDATED || client.updates != 1 {
+		t.Errorf("expected the process to be updated, got %s with %d updates", result, client.updates)
+	}
+}
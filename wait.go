@@ -0,0 +1,130 @@
+package coreclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+const (
+	waitMinInterval = 250 * time.Millisecond
+	waitMaxInterval = 5 * time.Second
+)
+
+// StateError is returned by the wait helpers if a process reached a terminal state that
+// hasn't been waited for.
+type StateError struct {
+	ID      string
+	State   string
+	LastLog string
+}
+
+func (e StateError) Error() string {
+	return fmt.Sprintf("process %s is %s: %s", e.ID, e.State, e.LastLog)
+}
+
+// WaitForState polls the state of the process with the given ID until its exec state is one
+// of the given states. It returns early with a StateError if the process is failed or killed
+// and this state is not waited for. The polling interval backs off exponentially.
+func WaitForState(ctx context.Context, client RestClient, id string, states ...string) (api.ProcessState, error) {
+	var state api.ProcessState
+
+	err := poll(ctx, func() (bool, error) {
+		var err error
+
+		state, err = client.ProcessState(id)
+		if err != nil {
+			return false, err
+		}
+
+		for _, s := range states {
+			if state.State == s {
+				return true, nil
+			}
+		}
+
+		if state.State == "failed" || state.State == "killed" {
+			return false, StateError{
+				ID:      id,
+				State:   state.State,
+				LastLog: state.LastLog,
+			}
+		}
+
+		return false, nil
+	})
+
+	return state, err
+}
+
+// WaitForProgress polls the state of the process with the given ID until it is running and
+// its progress advances, i.e. the number of processed frames or packets increases.
+func WaitForProgress(ctx context.Context, client RestClient, id string) (api.ProcessState, error) {
+	var state api.ProcessState
+	var last *api.Progress
+
+	err := poll(ctx, func() (bool, error) {
+		var err error
+
+		state, err = client.ProcessState(id)
+		if err != nil {
+			return false, err
+		}
+
+		if state.State == "failed" || state.State == "killed" || state.State == "finished" {
+			return false, StateError{
+				ID:      id,
+				State:   state.State,
+				LastLog: state.LastLog,
+			}
+		}
+
+		if state.State != "running" || state.Progress == nil {
+			last = nil
+			return false, nil
+		}
+
+		if last != nil && (state.Progress.Frame > last.Frame || state.Progress.Packet > last.Packet) {
+			return true, nil
+		}
+
+		last = state.Progress
+
+		return false, nil
+	})
+
+	return state, err
+}
+
+// poll calls fn with an exponentially increasing interval until it returns true, an error,
+// or the context is done.
+func poll(ctx context.Context, fn func() (bool, error)) error {
+	interval := waitMinInterval
+
+	for {
+		done, err := fn()
+		if err != nil {
+			return err
+		}
+
+		if done {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
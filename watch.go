@@ -0,0 +1,229 @@
+package coreclient
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/datarhei/core-client-go/v16/api"
+)
+
+// ProcessEventType is the type of a change of a process.
+type ProcessEventType string
+
+const (
+	PROCESS_EVENT_ADDED          ProcessEventType = "added"
+	PROCESS_EVENT_REMOVED        ProcessEventType = "removed"
+	PROCESS_EVENT_CONFIG_CHANGED ProcessEventType = "config_changed"
+	PROCESS_EVENT_ORDER_CHANGED  ProcessEventType = "order_changed"
+	PROCESS_EVENT_STATE_CHANGED  ProcessEventType = "state_changed"
+	PROCESS_EVENT_RESTARTED      ProcessEventType = "restarted"
+	PROCESS_EVENT_STALLED        ProcessEventType = "stalled"
+	PROCESS_EVENT_ERROR          ProcessEventType = "error"
+)
+
+// ProcessEvent is a change of a process between two snapshots of the process list.
+type ProcessEvent struct {
+	Type ProcessEventType
+	ID   string
+	Time time.Time
+
+	// Previous is the process in the previous snapshot. It is nil for added processes.
+	Previous *api.Process
+
+	// Current is the process in the current snapshot. It is nil for removed processes.
+	Current *api.Process
+
+	// Err is the error that occurred while fetching the process list, only set for PROCESS_EVENT_ERROR.
+	Err error
+}
+
+// WatchOptions are the options for WatchProcesses.
+type WatchOptions struct {
+	// Interval is the time between two snapshots of the process list. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// List selects the processes to watch. The filter will always include "config" and "state".
+	List ProcessListOptions
+}
+
+// WatchProcesses periodically fetches the process list and emits an event for every change
+// between two successive snapshots. The returned channel will be closed when the context is done.
+// Errors while fetching the process list are emitted as events and the watching continues.
+func WatchProcesses(ctx context.Context, client RestClient, opts WatchOptions) <-chan ProcessEvent {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	opts.List.Filter = withFilter(opts.List.Filter, "config", "state")
+
+	ch := make(chan ProcessEvent)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		var previous map[string]api.Process
+		stalled := map[string]struct{}{}
+
+		for {
+			processes, err := client.ProcessList(opts.List)
+			now := time.Now()
+
+			var events []ProcessEvent
+
+			if err != nil {
+				events = append(events, ProcessEvent{
+					Type: PROCESS_EVENT_ERROR,
+					Time: now,
+					Err:  err,
+				})
+			} else {
+				current := make(map[string]api.Process, len(processes))
+				for _, p := range processes {
+					current[p.ID] = p
+				}
+
+				if previous != nil {
+					events = diffProcesses(previous, current, processes, stalled, now)
+				}
+
+				previous = current
+			}
+
+			for _, e := range events {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return ch
+}
+
+// diffProcesses computes the events between two snapshots. The list is used to emit the
+// events in the order of the current process list, removed processes follow ordered by their ID.
+// The stalled processes are tracked in stalled, such that a process is only reported once when
+// its progress stops advancing.
+func diffProcesses(previous, current map[string]api.Process, list []api.Process, stalled map[string]struct{}, now time.Time) []ProcessEvent {
+	events := []ProcessEvent{}
+
+	newEvent := func(t ProcessEventType, id string, prev, curr *api.Process) {
+		events = append(events, ProcessEvent{
+			Type:     t,
+			ID:       id,
+			Time:     now,
+			Previous: prev,
+			Current:  curr,
+		})
+	}
+
+	for i := range list {
+		curr := list[i]
+
+		prev, ok := previous[curr.ID]
+		if !ok {
+			newEvent(PROCESS_EVENT_ADDED, curr.ID, nil, &curr)
+			continue
+		}
+
+		if curr.Config != nil && prev.Config != nil && !ProcessConfigEqual(*prev.Config, *curr.Config) {
+			newEvent(PROCESS_EVENT_CONFIG_CHANGED, curr.ID, &prev, &curr)
+		}
+
+		if curr.State == nil || prev.State == nil {
+			delete(stalled, curr.ID)
+			continue
+		}
+
+		if curr.State.Order != prev.State.Order {
+			newEvent(PROCESS_EVENT_ORDER_CHANGED, curr.ID, &prev, &curr)
+		}
+
+		isStalling := false
+
+		if curr.State.State != prev.State.State {
+			newEvent(PROCESS_EVENT_STATE_CHANGED, curr.ID, &prev, &curr)
+		} else if curr.State.State == "running" {
+			if curr.State.Runtime < prev.State.Runtime {
+				newEvent(PROCESS_EVENT_RESTARTED, curr.ID, &prev, &curr)
+			} else if isStalled(prev.State.Progress, curr.State.Progress) {
+				isStalling = true
+
+				if _, ok := stalled[curr.ID]; !ok {
+					newEvent(PROCESS_EVENT_STALLED, curr.ID, &prev, &curr)
+				}
+			}
+		}
+
+		if isStalling {
+			stalled[curr.ID] = struct{}{}
+		} else {
+			delete(stalled, curr.ID)
+		}
+	}
+
+	removed := []string{}
+
+	for id := range previous {
+		if _, ok := current[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	sort.Strings(removed)
+
+	for _, id := range removed {
+		prev := previous[id]
+		newEvent(PROCESS_EVENT_REMOVED, id, &prev, nil)
+		delete(stalled, id)
+	}
+
+	return events
+}
+
+// isStalled returns whether the progress didn't advance between two samples.
+func isStalled(prev, curr *api.Progress) bool {
+	if prev == nil || curr == nil {
+		return false
+	}
+
+	return curr.Frame == prev.Frame && curr.Packet == prev.Packet
+}
+
+// withFilter returns the filter with the given fields added, if they are not yet present.
+// An empty filter already selects all fields and will be returned unchanged.
+func withFilter(filter []string, fields ...string) []string {
+	if len(filter) == 0 {
+		return filter
+	}
+
+	f := append([]string{}, filter...)
+
+	for _, field := range fields {
+		found := false
+		for _, x := range f {
+			if x == field {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			f = append(f, field)
+		}
+	}
+
+	return f
+}